@@ -6,5 +6,6 @@ require (
 	github.com/ergochat/irc-go v0.0.0-20210805030750-d6a5f43c673d
 	github.com/haya14busa/goplay v1.0.0
 	github.com/pelletier/go-toml v1.9.3
+	golang.org/x/net v0.0.0-20210405180319-a5a99cb37ef4
 	golang.org/x/tools v0.1.5
 )
@@ -0,0 +1,55 @@
+package bot
+
+import "github.com/ergochat/irc-go/ircmsg"
+
+// isAdmin returns whether or not the sender of msg is an admin. If admin_accounts is set and the network tags
+// messages with the sender's account, the account must be one of them, as masks can be spoofed with a cloak.
+// Otherwise, the sender's nick!user@host must match one of the admin masks.
+func (b *Bot) isAdmin(msg ircmsg.Message) bool {
+	if accounts := b.cfg().AdminAccounts; len(accounts) > 0 && b.accountsSupported() {
+		present, account := msg.GetTag("account")
+		return present && account != "*" && containsFold(accounts, account)
+	}
+
+	return b.matchesAdminMask(msg.Prefix)
+}
+
+// accountsSupported returns whether or not the server tags messages with the sender's account.
+func (b *Bot) accountsSupported() bool {
+	return b.hasCap("account-tag")
+}
+
+// matchesAdminMask returns whether or not prefix (nick!user@host) matches any configured admin mask.
+func (b *Bot) matchesAdminMask(prefix string) bool {
+	b.configMu.RLock()
+	masks := b.adminMasks
+	b.configMu.RUnlock()
+
+	for _, re := range masks {
+		if re.MatchString(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConfigCmd is the callback for the ~config IRC command. It sends the redacted config to the invoking user in private
+// messages, regardless of where it was invoked. It is never pasted, as a paste could be seen by anyone with the link,
+// so every line is sent regardless of MaxReplyLines.
+func (b *Bot) ConfigCmd(ctx *CommandContext, _ string, _ ReplyFunc) {
+	text := b.cfg().Redacted()
+
+	// Every message holds at least one byte, so there are never more messages than this
+	if err := b.sendSplit(ctx.Nick, text, len(text)); err != nil {
+		errorf("Unable to send config: %s", err)
+	}
+}
+
+// QuitCmd is the callback for the ~quit IRC command, and disconnects the bot with the given reason.
+func (b *Bot) QuitCmd(ctx *CommandContext, args string, _ ReplyFunc) {
+	warnf("Quitting at the request of %s", ctx.Source)
+
+	// Stop waits for running commands, don't block the connection while it does
+	go b.Stop(args)
+}
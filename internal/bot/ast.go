@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// ASTCmd is the callback for the ~ast IRC command. It parses the given go expression, statements, or file, and
+// responds with a compact representation of the resulting AST.
+func (b *Bot) ASTCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	if strings.TrimSpace(args) == "" {
+		reply("Cannot parse empty code")
+		return
+	}
+
+	nodes, err := parseFragment(args)
+	if err != nil {
+		reply("Parse failed: %s", err)
+		return
+	}
+
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, compactAST(n))
+	}
+
+	reply("%s", strings.Join(out, "; "))
+}
+
+// parseFragment parses src as an expression, a list of statements, or a full file, in that order, returning the
+// first that succeeds. If none succeed, the error from parsing as statements is returned, as that is usually the
+// most useful.
+func parseFragment(src string) ([]ast.Node, error) {
+	if expr, err := parser.ParseExpr(src); err == nil {
+		return []ast.Node{expr}, nil
+	}
+
+	fset := token.NewFileSet()
+	if f, err := parser.ParseFile(fset, "prog.go", src, 0); err == nil {
+		return []ast.Node{f}, nil
+	}
+
+	// Statements must be wrapped in a function to be parsed, the wrapper is then discarded.
+	f, err := parser.ParseFile(fset, "prog.go", "package p; func _() {\n"+src+"\n}", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, stmt := range f.Decls[0].(*ast.FuncDecl).Body.List {
+		out = append(out, stmt)
+	}
+
+	return out, nil
+}
+
+// compactAST renders an AST as nested TypeName(children...) groups, with identifiers, literals, and operators
+// inlined.
+func compactAST(root ast.Node) string {
+	sb := &strings.Builder{}
+	needSpace := false
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			sb.WriteByte(')')
+			needSpace = true
+			return false
+		}
+
+		if needSpace {
+			sb.WriteByte(' ')
+		}
+
+		sb.WriteString(reflect.TypeOf(n).Elem().Name())
+		sb.WriteByte('(')
+		needSpace = false
+
+		detail := ""
+		switch n := n.(type) {
+		case *ast.Ident:
+			detail = n.Name
+		case *ast.BasicLit:
+			detail = n.Value
+		case *ast.BinaryExpr:
+			detail = n.Op.String()
+		case *ast.UnaryExpr:
+			detail = n.Op.String()
+		case *ast.AssignStmt:
+			detail = n.Tok.String()
+		case *ast.IncDecStmt:
+			detail = n.Tok.String()
+		case *ast.BranchStmt:
+			detail = n.Tok.String()
+		}
+
+		if detail != "" {
+			sb.WriteString(detail)
+			needSpace = true
+		}
+
+		return true
+	})
+
+	return sb.String()
+}
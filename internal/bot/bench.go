@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// benchFlags are the flags that can precede the links passed to ~bench.
+var benchFlags = []flagSpec{{name: "n", hasValue: true}, {name: "backend", hasValue: true}}
+
+const (
+	defaultBenchIterations = 1000
+	maxBenchIterations     = 10000000
+)
+
+// benchMain replaces the snippet's main function, and runs each benchmark in turn. The playground has a fake clock
+// that only advances when sleeping, so benchmarks are run a fixed number of times rather than for a duration, and
+// ns/op is only reported if any time passed at all.
+const benchMain = `
+
+func main() {
+	testing.Init()
+	flag.Set("test.benchtime", "%dx")
+	for _, bench := range []struct {
+		name string
+		fn   func(*testing.B)
+	}{%s} {
+		r := testing.Benchmark(bench.fn)
+		if r.N == 0 {
+			fmt.Printf("%%s: failed\n", bench.name)
+			continue
+		}
+
+		timing := ""
+		if r.T > 0 {
+			timing = fmt.Sprintf("%%d ns/op, ", r.NsPerOp())
+		}
+
+		fmt.Printf("%%s: %%s%%d B/op, %%d allocs/op\n", bench.name, timing, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+}
+`
+
+// buildBenchHarness turns code containing benchmark functions into a program that runs them with testing.Benchmark,
+// as the playground cannot run go test -bench. Any main function in code is removed, and its package renamed to main.
+func buildBenchHarness(code string, iterations int) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "prog.go", code, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	var mainStart, mainEnd token.Pos
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+
+		switch {
+		case fn.Name.Name == "main":
+			mainStart, mainEnd = fn.Pos(), fn.End()
+			if fn.Doc != nil {
+				mainStart = fn.Doc.Pos()
+			}
+
+		case isBenchmark(fn):
+			names = append(names, fn.Name.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "", errors.New("no benchmark functions found, expected func BenchmarkXxx(b *testing.B)")
+	}
+
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	var benches strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&benches, "{%q, %s}, ", name, name)
+	}
+
+	out := code + fmt.Sprintf(benchMain, iterations, benches.String())
+	if mainStart.IsValid() {
+		out = out[:offset(mainStart)] + out[offset(mainEnd):]
+	}
+
+	nameStart, nameEnd := offset(f.Name.Pos()), offset(f.Name.End())
+	return out[:nameStart] + "main" + out[nameEnd:], nil
+}
+
+// isBenchmark returns whether fn is a benchmark, as go test would run it: named BenchmarkXxx, where Xxx does not start
+// with a lower case letter, and taking a single *testing.B.
+func isBenchmark(fn *ast.FuncDecl) bool {
+	suffix := strings.TrimPrefix(fn.Name.Name, "Benchmark")
+	if suffix == fn.Name.Name {
+		return false
+	}
+
+	if r, _ := utf8.DecodeRuneInString(suffix); unicode.IsLower(r) {
+		return false
+	}
+
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 || fn.Type.Results != nil {
+		return false
+	}
+
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "B"
+}
+
+// BenchCmd is the callback for the ~bench IRC command. It runs the benchmark functions in the given snippets on the
+// playground, and responds with their allocations, and timings where the playground's clock allows.
+func (b *Bot) BenchCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	flags, args, err := parseFlags(args, benchFlags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	iterations := defaultBenchIterations
+	if n, ok := flags["n"]; ok {
+		iterations, err = strconv.Atoi(n)
+		if err != nil || iterations < 1 || iterations > maxBenchIterations {
+			reply("Invalid iteration count: must be between 1 and %d", maxBenchIterations)
+			return
+		}
+	}
+
+	backend, err := b.backendFor(flags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	b.forEachSnippet(args, reply, func(code string) string {
+		harness, err := buildBenchHarness(code, iterations)
+		if err != nil {
+			return "Unable to benchmark: " + err.Error()
+		}
+
+		// Resolving imports adds those the harness needs, and drops any only used by the removed main function
+		runRes, _, err := b.runCode(context.Background(), harness, nil, backend, false, true, true)
+		if err != nil {
+			errorf("Unable to start compile: %s", err)
+			ctx.Fail(err)
+			return "Unable to start compile: " + explainOrTrim(err.Error())
+		}
+
+		if len(runRes.Errors) != 0 {
+			debugf("Error while running compile: %s", runRes.Errors)
+			return "Compile failed! " + b.formatCompileErrors(runRes.Errors)
+		}
+
+		if runRes.Status != 0 {
+			return "Benchmark failed: " + b.formatRunResult(runRes)
+		}
+
+		results := strings.Split(strings.TrimSpace(joinEvents(runRes)), "\n")
+		return sanitizeOutput(strings.Join(results, "; "), b.cfg().SanitizeOutput)
+	})
+}
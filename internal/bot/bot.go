@@ -2,19 +2,30 @@ package bot
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"go/parser"
+	"go/token"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"unicode"
+	"sync"
+	"text/template"
+	"time"
 	"unicode/utf8"
 
 	"github.com/ergochat/irc-go/ircevent"
 	"github.com/ergochat/irc-go/ircmsg"
-	"github.com/haya14busa/goplay"
 	"golang.org/x/tools/imports"
 )
 
@@ -23,404 +34,1886 @@ type BotConfig struct {
 	Nick            string `toml:"nick"`
 	User            string `toml:"user"`
 	RealName        string `toml:"real_name"`
-	VersionResponse string `toml:"-"`
+	VersionResponse string `toml:"version_response"` // The reply to CTCP VERSION
 	SASLUser        string `toml:"sasl_user"`
 	SASLPassword    string `toml:"sasl_password"`
-	CommandPrefix   string `toml:"command_prefix"`
 
-	Server       string   `toml:"server"`
-	UseTLS       bool     `toml:"use_tls"`
-	JoinChannels []string `toml:"join_channels"`
-	Debug        bool     `toml:"debug"`
+	// On networks without SASL, the bot identifies by messaging NickServ instead. NickServUser is only needed if the
+	// account name differs from Nick
+	NickServPassword string `toml:"nickserv_password"`
+	NickServUser     string `toml:"nickserv_user"`
+	NickServNick     string `toml:"nickserv_nick"`
+
+	CommandPrefix string `toml:"command_prefix"`
+
+	// ChannelPrefixes overrides CommandPrefix in specific channels, for channels where it conflicts with another bot
+	ChannelPrefixes map[string]string `toml:"channel_prefixes"`
+
+	// PreferPrefixTrigger makes the command prefix take precedence over addressing the bot by nick, for messages that
+	// could be either
+	PreferPrefixTrigger bool `toml:"prefer_prefix_trigger"`
+
+	Admins  []string `toml:"admins"`  // nick!user@host glob masks
+	Ignores []string `toml:"ignores"` // nick!user@host glob masks whose commands are silently dropped
+
+	// AdminAccounts are the services accounts of admins. Where the network tags messages with accounts, these are
+	// used instead of Admins, which are otherwise used as a fallback
+	AdminAccounts []string `toml:"admin_accounts"`
+
+	Aliases map[string]string `toml:"aliases"` // Additional names for commands, eg { "r" = "playrun" }
+
+	// MaxSourceBytes limits the size of code passed to ~eval, or downloaded for ~playrun and friends
+	MaxSourceBytes int `toml:"max_source_bytes"`
+
+	// AllowedChannels restricts the channels the bot responds to commands in, if set. Private messages are unaffected
+	AllowedChannels []string `toml:"allowed_channels"`
+
+	// TLSInsecureSkipVerify disables verification of the server's certificate. Prefer CACertFile where possible
+	TLSInsecureSkipVerify bool `toml:"tls_insecure_skip_verify"`
+
+	Server         string        `toml:"server"`
+	UseTLS         bool          `toml:"use_tls"`
+	CACertFile     string        `toml:"ca_cert_file"`
+	TLSCertFile    string        `toml:"tls_cert_file"` // Client certificate, for networks that support CertFP
+	TLSKeyFile     string        `toml:"tls_key_file"`
+	TLSServerName  string        `toml:"tls_server_name"` // Overrides the name the server's certificate is checked against
+	Proxy          string        `toml:"proxy"`           // socks5://[user:pass@]host:port, for IRC and the playground
+	MaxReplyLines  int           `toml:"max_reply_lines"`
+	ConnectTimeout time.Duration `toml:"connect_timeout"`
+	PingInterval   time.Duration `toml:"ping_interval"` // How often to PING the server, at least ConnectTimeout
+	JoinChannels   []string      `toml:"join_channels"` // "#channel", or "#channel key" for channels with a key
+	AutoPartAfter  time.Duration `toml:"auto_part_after"`
+	Debug          bool          `toml:"debug"`           // Logs raw IRC traffic, and implies a log_level of debug
+	LogLevel       string        `toml:"log_level"`       // debug, info, warn, or error
+	SanitizeOutput string        `toml:"sanitize_output"` // See SanitizeSuppress and SanitizeStrip
+	Formatter      string        `toml:"formatter"`       // See FormatterLocal and FormatterPlayground
+
+	// CommandCooldown is how long a user must wait between uses of a command that uses the playground. Negative
+	// values disable the cooldown
+	CommandCooldown time.Duration `toml:"command_cooldown"`
+
+	// Multi-line output is uploaded to PasteURL, as a multipart form file in the field PasteField
+	PasteURL   string `toml:"paste_url"`
+	PasteField string `toml:"paste_field"`
+
+	// After BreakerThreshold consecutive failures to reach the playground, requests are refused for BreakerCooldown
+	BreakerThreshold int           `toml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `toml:"breaker_cooldown"`
+
+	// Runs with output exceeding either of these are warned about
+	LargeOutputEvents int `toml:"large_output_events"`
+	LargeOutputBytes  int `toml:"large_output_bytes"`
+
+	MaxSnippets int `toml:"max_snippets"` // the most snippets a single ~play or ~playrun will process
+
+	// ShutdownGrace is how long in-flight commands are given to finish when the bot is stopped
+	ShutdownGrace time.Duration `toml:"shutdown_grace"`
+
+	// Commands that use the playground are run by Workers goroutines, with up to QueueSize more waiting for one
+	Workers   int `toml:"workers"`
+	QueueSize int `toml:"queue_size"`
+
+	// MaxConcurrentCompiles limits how many requests are made to the playground at once, across all commands
+	MaxConcurrentCompiles int `toml:"max_concurrent_compiles"`
+
+	// CompileTimeout is how long to wait for the playground to respond to a single command
+	CompileTimeout time.Duration `toml:"compile_timeout"`
+
+	// PlaygroundBackend is the backend code is run on, unless overridden with --backend: empty for the current
+	// release, gotip for the development tree, or goprev for the previous release
+	PlaygroundBackend string `toml:"playground_backend"`
+
+	// EvalTemplate is a text/template that code passed to ~eval is wrapped in, with the code as {{.Code}}. It defaults to
+	// a bare main function
+	EvalTemplate string `toml:"eval_template"`
+
+	// Code importing any of DisallowedImports is rejected without being sent to the playground, as is code containing
+	// an empty infinite loop if RejectEmptyLoops is set. Imports ending in /... also match everything below them
+	DisallowedImports []string `toml:"disallowed_imports"`
+	RejectEmptyLoops  bool     `toml:"reject_empty_loops"`
+
+	// LastResultTTL is how long ~last remembers the most recent ~eval in each channel for
+	LastResultTTL time.Duration `toml:"last_result_ttl"`
+
+	// HintNoOutput adds a tip to ~eval replies with no output, if the code doesn't appear to print anything
+	HintNoOutput bool `toml:"hint_no_output"`
+
+	// ShareOnEval and ShareOnPlayrun control whether ~eval and ~playrun create a share link for the code they run.
+	// Sharing is an extra request to the playground, so can be turned off. They are pointers so that an explicit
+	// false can be told apart from unset, and are always set once defaulted
+	ShareOnEval    *bool `toml:"share_on_eval"`
+	ShareOnPlayrun *bool `toml:"share_on_playrun"`
+
+	// ShowRunTime reports how long the playground took in every reply to ~eval and ~playrun, as the --time flag does
+	ShowRunTime bool `toml:"show_run_time"`
+
+	// StartupSelfTest runs an empty program on the playground once first connected, and logs whether it worked
+	StartupSelfTest bool `toml:"startup_self_test"`
+
+	// StateFile is where ~save writes channels joined and ignores added at runtime, to be restored on startup
+	StateFile string `toml:"state_file"`
+
+	// HTTPTimeout limits how long any single HTTP request, such as downloading a snippet, can take
+	HTTPTimeout time.Duration `toml:"http_timeout"`
+
+	// MetricsAddr is the address to serve Prometheus metrics on, at /metrics. Metrics are not served if it is empty
+	MetricsAddr string `toml:"metrics_addr"`
+
+	// Up to CacheSize playground results are cached for CacheTTL, so that repeated runs of the same code are fast.
+	// Negative sizes disable the cache
+	CacheSize int           `toml:"cache_size"`
+	CacheTTL  time.Duration `toml:"cache_ttl"`
+
+	// Replies are sent at up to MessagesPerSecond, after an initial burst of MessageBurst lines, to avoid being
+	// disconnected for flooding. Negative rates disable the throttle
+	MessagesPerSecond float64 `toml:"messages_per_second"`
+	MessageBurst      int     `toml:"message_burst"`
+
+	// Failed connection attempts are retried after ReconnectDelay, doubling for each consecutive failure up to
+	// ReconnectMaxDelay. After ReconnectMaxFailures consecutive failures the bot gives up on the network, and the
+	// process exits once every network has been given up on. Negative values retry forever
+	ReconnectDelay       time.Duration `toml:"reconnect_delay"`
+	ReconnectMaxDelay    time.Duration `toml:"reconnect_max_delay"`
+	ReconnectMaxFailures int           `toml:"reconnect_max_failures"`
+
+	// Networks, if set, connects to several networks from one process, see NewGroup. Anything a network doesn't
+	// set is taken from the settings above
+	Networks []NetworkConfig `toml:"networks"`
+
+	path    string // the file the config was loaded from, if any
+	network string // the name of the network in Networks this config is for, if any
+}
+
+// prefixFor returns the command prefix used in channel.
+func (c *BotConfig) prefixFor(channel string) string {
+	for ch, prefix := range c.ChannelPrefixes {
+		if strings.EqualFold(ch, channel) {
+			return prefix
+		}
+	}
+
+	return c.CommandPrefix
+}
+
+// useSASL returns whether or not the bot authenticates with SASL.
+func (c *BotConfig) useSASL() bool {
+	return c.SASLPassword != "" && c.SASLUser != ""
+}
+
+// channelAllowed returns whether commands may be run in channel, according to AllowedChannels.
+func (c *BotConfig) channelAllowed(channel string) bool {
+	if len(c.AllowedChannels) == 0 {
+		return true
+	}
+
+	for _, ch := range c.AllowedChannels {
+		if strings.EqualFold(ch, channel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+const redacted = "[REDACTED]"
+
+// redactSecret returns redacted in place of secret, unless secret is empty, so that unset secrets are still visible.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// Redacted returns the config as key=value lines, named as in the config file, with any secrets removed. It is safe
+// to log or send to IRC.
+func (c *BotConfig) Redacted() string {
+	cpy := *c
+	cpy.SASLPassword = redactSecret(cpy.SASLPassword)
+	cpy.NickServPassword = redactSecret(cpy.NickServPassword)
+
+	cpy.Networks = make([]NetworkConfig, len(c.Networks))
+	for i, n := range c.Networks {
+		n.SASLPassword = redactSecret(n.SASLPassword)
+		n.NickServPassword = redactSecret(n.NickServPassword)
+		cpy.Networks[i] = n
+	}
+
+	cpy.JoinChannels = make([]string, len(c.JoinChannels))
+	for i, entry := range c.JoinChannels {
+		if channel, key := splitChannelKey(entry); key != "" {
+			entry = channel + " " + redacted
+		}
+
+		cpy.JoinChannels[i] = entry
+	}
+
+	if u, err := url.Parse(cpy.Proxy); err == nil && u.User != nil {
+		cpy.Proxy = u.Redacted()
+	}
+
+	return strings.Join(appendConfigLines(nil, "", reflect.ValueOf(cpy), false), "\n")
+}
+
+// appendConfigLines appends a key=value line to lines for each field of the struct v, named after its toml tag and
+// prefixed with prefix. Slices of structs, such as Networks, are listed field by field with their index in the prefix.
+// If skipZero is set, fields left unset are skipped.
+func appendConfigLines(lines []string, prefix string, v reflect.Value, skipZero bool) []string {
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("toml")
+		field := v.Field(i)
+		if name == "" || (skipZero && field.IsZero()) {
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < field.Len(); j++ {
+				lines = appendConfigLines(lines, fmt.Sprintf("%s%s[%d].", prefix, name, j), field.Index(j), true)
+			}
+
+			continue
+
+		case field.Kind() == reflect.Ptr && !field.IsNil():
+			field = field.Elem()
+		}
+
+		format := "%s%s=%v"
+		kind := field.Kind()
+		if kind == reflect.String || kind == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+			format = "%s%s=%q"
+		}
+
+		lines = append(lines, fmt.Sprintf(format, prefix, name, field.Interface()))
+	}
+
+	return lines
+}
+
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultPingInterval   = 4 * time.Minute
+	defaultMaxReplyLines  = 3
+
+	defaultCommandCooldown = 5 * time.Second
+	defaultPasteField      = "file"
+
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = time.Minute
+
+	defaultLargeOutputEvents = 100
+	defaultLargeOutputBytes  = 16 * 1024
+
+	defaultMaxSnippets = 3
+
+	defaultMaxSourceBytes = 64 << 10 // the playground's own limit
+
+	defaultShutdownGrace = 5 * time.Second
+
+	defaultWorkers   = 4
+	defaultQueueSize = 32
+
+	defaultMaxConcurrentCompiles = 2
+	defaultCompileTimeout        = 30 * time.Second
+
+	defaultStateFile = "state.toml"
+
+	defaultLastResultTTL = time.Hour
+
+	defaultHTTPTimeout = time.Minute
+
+	defaultCacheSize = 100
+	defaultCacheTTL  = 10 * time.Minute
+
+	defaultMessagesPerSecond = 1
+	defaultMessageBurst      = 4
+
+	defaultReconnectDelay       = 5 * time.Second
+	defaultReconnectMaxDelay    = 5 * time.Minute
+	defaultReconnectMaxFailures = 10
+
+	defaultShareOnEval    = true
+	defaultShareOnPlayrun = false
+)
+
+// setDefaults fills in any unset fields that have a default value
+func (c *BotConfig) setDefaults() {
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = defaultConnectTimeout
+	}
+
+	if c.PingInterval == 0 {
+		c.PingInterval = defaultPingInterval
+	}
+
+	if c.VersionResponse == "" {
+		c.VersionResponse = "goplay-irc " + Version
+	}
+
+	if c.NickServNick == "" {
+		c.NickServNick = defaultNickServNick
+	}
+
+	if c.MaxReplyLines == 0 {
+		c.MaxReplyLines = defaultMaxReplyLines
+	}
+
+	if c.LogLevel == "" {
+		c.LogLevel = LevelInfo.String()
+	}
+
+	if c.SanitizeOutput == "" {
+		c.SanitizeOutput = SanitizeSuppress
+	}
+
+	if c.Formatter == "" {
+		c.Formatter = FormatterLocal
+	}
+
+	if c.CommandCooldown == 0 {
+		c.CommandCooldown = defaultCommandCooldown
+	}
+
+	if c.PasteField == "" {
+		c.PasteField = defaultPasteField
+	}
+
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = defaultBreakerThreshold
+	}
+
+	if c.BreakerCooldown == 0 {
+		c.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	if c.LargeOutputEvents == 0 {
+		c.LargeOutputEvents = defaultLargeOutputEvents
+	}
+
+	if c.LargeOutputBytes == 0 {
+		c.LargeOutputBytes = defaultLargeOutputBytes
+	}
+
+	if c.MaxSnippets == 0 {
+		c.MaxSnippets = defaultMaxSnippets
+	}
+
+	if c.MaxSourceBytes == 0 {
+		c.MaxSourceBytes = defaultMaxSourceBytes
+	}
+
+	if c.ShutdownGrace == 0 {
+		c.ShutdownGrace = defaultShutdownGrace
+	}
+
+	if c.Workers == 0 {
+		c.Workers = defaultWorkers
+	}
+
+	if c.QueueSize == 0 {
+		c.QueueSize = defaultQueueSize
+	}
+
+	if c.MaxConcurrentCompiles == 0 {
+		c.MaxConcurrentCompiles = defaultMaxConcurrentCompiles
+	}
+
+	if c.CompileTimeout == 0 {
+		c.CompileTimeout = defaultCompileTimeout
+	}
+
+	if c.StateFile == "" {
+		c.StateFile = defaultStateFile
+	}
+
+	if c.LastResultTTL == 0 {
+		c.LastResultTTL = defaultLastResultTTL
+	}
+
+	if c.HTTPTimeout == 0 {
+		c.HTTPTimeout = defaultHTTPTimeout
+	}
+
+	if c.CacheSize == 0 {
+		c.CacheSize = defaultCacheSize
+	}
+
+	if c.CacheTTL == 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+
+	if c.MessagesPerSecond == 0 {
+		c.MessagesPerSecond = defaultMessagesPerSecond
+	}
+
+	if c.MessageBurst == 0 {
+		c.MessageBurst = defaultMessageBurst
+	}
+
+	if c.ReconnectDelay == 0 {
+		c.ReconnectDelay = defaultReconnectDelay
+	}
+
+	if c.ReconnectMaxDelay == 0 {
+		c.ReconnectMaxDelay = defaultReconnectMaxDelay
+	}
+
+	if c.ReconnectMaxFailures == 0 {
+		c.ReconnectMaxFailures = defaultReconnectMaxFailures
+	}
+
+	if c.ShareOnEval == nil {
+		share := defaultShareOnEval
+		c.ShareOnEval = &share
+	}
+
+	if c.ShareOnPlayrun == nil {
+		share := defaultShareOnPlayrun
+		c.ShareOnPlayrun = &share
+	}
+}
+
+// Bot is an IRC bot and command handler
+type Bot struct {
+	configMu   sync.RWMutex
+	config     *BotConfig
+	adminMasks []*regexp.Regexp // config.Admins, compiled once rather than for every message
+
+	// irc is only used directly to manage the connection and register callbacks. Everything involved in handling
+	// commands goes through conn instead, which is irc except in local mode, see RunLocal
+	irc   *ircevent.Connection
+	conn  ircConn
+	local bool // set by RunLocal, cooldowns don't apply so that commands can be tried out quickly
+
+	ircProxy *ircProxy // carries the IRC connection if a proxy is configured, see armProxy
+
+	commands map[string]*Command
+	jobs     chan func() // commands waiting for a worker, see enqueue
+
+	playgroundSem chan struct{} // limits concurrent requests to the playground, see callPlayground
+	httpClient    *http.Client  // for all requests to the playground, and for downloading snippets
+	metrics       *metrics
+	stats         *commandStats
+	cache         *resultCache
+	throttle      *throttle // paces replies, see SplitReply
+
+	channelsMu sync.Mutex
+	channels   map[string]time.Time // joined channels, and when a command was last run in them
+	// channels joined at runtime by admins, keyed by lowercase name, with any key as in JoinChannels. These are
+	// rejoined on reconnect
+	extraChannels map[string]string
+	// channels joined at runtime with "~join --pin", keyed by lowercase name. Like JoinChannels, these are never
+	// parted for being idle
+	pinnedChannels map[string]bool
+
+	presenceMu   sync.Mutex
+	adminsOnline map[string]bool // keyed by lowercase nick
+
+	lastEvalsMu sync.Mutex
+	lastEvals   map[string]string // the last code passed to ~eval, keyed by CommandContext.UserKey
+
+	lastResultsMu sync.Mutex
+	lastResults   map[string]*lastResult // the last successful ~eval, keyed by lowercase reply target, see ~last
+
+	ignoresMu sync.Mutex
+	ignores   map[string]*ignore // keyed by lowercase mask
+
+	breaker *circuitBreaker
+	paster  Paster
+
+	cooldownsMu sync.Mutex
+	cooldowns   map[cooldownKey]*cooldownEntry
+
+	hooksMu   sync.RWMutex
+	preHooks  []PreHook
+	postHooks []PostHook
+
+	inFlightMu sync.Mutex
+	inFlight   sync.WaitGroup // queued commands that have not yet completed
+	stopping   bool           // set by Stop, no new commands are queued once set
+
+	connectFailures int   // consecutive failed connection attempts, only accessed from Run and the irc library's Loop
+	gaveUp          error // why the bot stopped reconnecting, if it reached ReconnectMaxFailures
+
+	ctcpOnce     sync.Once
+	selfTestOnce sync.Once
+
+	capsMu sync.Mutex
+	caps   map[string]string // the capabilities acknowledged by the server, see hasCap
+
+	started     time.Time // when the bot was created, for ~uptime
+	connectedMu sync.Mutex
+	connectedAt time.Time // when the bot last connected, zero until the first connection
+	// the server's time at the end of registration, if it was tagged with it, see isReplayed
+	registeredAt time.Time
+	replayWarned bool // whether a replayed message has been logged as a warning since connecting
+
+	evalTemplate *template.Template // parsed from eval_template
+
+	primary *Bot // the bot whose workers and playground client this one shares, if any, see NewGroup
+
+	identifyMu sync.Mutex
+	identified chan struct{} // closed once a pending NickServ identify completes
+}
+
+// New creates a new bot with the given config. Configs with Networks must use NewGroup instead.
+func New(c *BotConfig) (*Bot, error) {
+	if len(c.Networks) > 0 {
+		return nil, errors.New("config has networks, use NewGroup")
+	}
+
+	return newBot(c, nil)
+}
+
+// newBot creates a new bot with the given config. If primary is not nil, the new bot shares its workers and
+// everything used to talk to the playground, rather than having its own.
+func newBot(c *BotConfig, primary *Bot) (*Bot, error) {
+	c.setDefaults()
+	if err := validateJoinChannels(c.JoinChannels); err != nil {
+		return nil, err
+	}
+
+	if c.SanitizeOutput != SanitizeSuppress && c.SanitizeOutput != SanitizeStrip {
+		return nil, fmt.Errorf("unknown sanitize_output level %q", c.SanitizeOutput)
+	}
+
+	if c.Formatter != FormatterLocal && c.Formatter != FormatterPlayground {
+		return nil, fmt.Errorf("unknown formatter %q", c.Formatter)
+	}
+
+	if c.ReconnectDelay < 0 || c.ReconnectMaxDelay < c.ReconnectDelay {
+		return nil, errors.New("reconnect_delay must be positive, and no more than reconnect_max_delay")
+	}
+
+	if c.Workers < 0 || c.QueueSize < 0 || c.MaxConcurrentCompiles < 0 {
+		return nil, errors.New("workers, queue_size, and max_concurrent_compiles cannot be negative")
+	}
+
+	if err := validateBackend(c.PlaygroundBackend); err != nil {
+		return nil, err
+	}
+
+	if c.MaxSnippets < 0 {
+		return nil, errors.New("max_snippets cannot be negative")
+	}
+
+	if c.MaxSourceBytes < 0 {
+		return nil, errors.New("max_source_bytes cannot be negative")
+	}
+
+	if c.MessageBurst < 0 {
+		return nil, errors.New("message_burst cannot be negative")
+	}
+
+	evalTemplate, err := parseEvalTemplate(c.EvalTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eval_template: %w", err)
+	}
+
+	level, err := ParseLogLevel(c.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Debug {
+		level = LevelDebug
+	}
+
+	SetLogLevel(level)
+
+	tlsConfig, err := makeTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TLSCertFile != "" {
+		if !c.UseTLS {
+			return nil, errors.New("tls_cert_file requires use_tls")
+		}
+
+		if !c.useSASL() {
+			// The irc library only supports SASL PLAIN, so the certificate can't be used for SASL EXTERNAL
+			warnf("SASL EXTERNAL is not supported, the network must recognise the client certificate by itself")
+		}
+	}
+
+	var proxy *url.URL
+	if c.Proxy != "" {
+		if proxy, err = parseProxy(c.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+	}
+
+	server := c.Server
+	var relay *ircProxy
+	if proxy != nil {
+		host, _, err := net.SplitHostPort(c.Server)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server: %w", err)
+		}
+
+		// The irc library connects to the local end of the proxy, so it needs to be told who to expect on the
+		// other end
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+
+		// Set before each connection attempt by armProxy, so that the server is never dialed directly
+		server = ""
+		relay = newIRCProxy(proxy, c.Server, c.ConnectTimeout)
+		infof("Connecting to %s through proxy %s", c.Server, proxy.Host)
+	}
+
+	conn := &ircevent.Connection{
+		Server:          server,
+		Nick:            c.Nick,
+		User:            c.User,
+		RealName:        c.RealName,
+		SASLLogin:       c.SASLUser,
+		SASLPassword:    c.SASLPassword,
+		Version:         c.VersionResponse,
+		UseTLS:          c.UseTLS,
+		TLSConfig:       tlsConfig,
+		Timeout:         c.ConnectTimeout,
+		KeepAlive:       c.PingInterval,
+		UseSASL:         c.useSASL(),
+		EnableCTCP:      true,
+		RequestCaps:     append([]string(nil), requestedCaps...),
+		AllowTruncation: true,
+		Debug:           c.Debug,
+	}
+
+	if c.Debug {
+		debugf("Effective config:\n%s", c.Redacted())
+	}
+
+	b := &Bot{
+		config:     c,
+		adminMasks: compileMasks(c.Admins),
+		irc:        conn,
+		conn:       conn,
+		ircProxy:   relay,
+		commands:   make(map[string]*Command),
+		channels:   make(map[string]time.Time),
+
+		extraChannels:  make(map[string]string),
+		pinnedChannels: make(map[string]bool),
+		adminsOnline:   make(map[string]bool),
+		lastEvals:      make(map[string]string),
+		lastResults:    make(map[string]*lastResult),
+		ignores:        make(map[string]*ignore),
+		breaker:        newCircuitBreaker(c.BreakerThreshold, c.BreakerCooldown),
+		cooldowns:      make(map[cooldownKey]*cooldownEntry),
+		jobs:           make(chan func(), c.QueueSize),
+		playgroundSem:  make(chan struct{}, c.MaxConcurrentCompiles),
+		metrics:        newMetrics(),
+		stats:          newCommandStats(),
+		cache:          newResultCache(c.CacheSize, c.CacheTTL),
+		throttle:       newThrottle(c.MessagesPerSecond, c.MessageBurst),
+		started:        time.Now(),
+		evalTemplate:   evalTemplate,
+	}
+
+	if primary != nil {
+		b.share(primary)
+	} else {
+		b.httpClient = newHTTPClient(proxy, c.HTTPTimeout)
+		if c.PasteURL != "" {
+			b.paster = newFormPaster(c.PasteURL, c.PasteField, b.httpClient.Transport)
+		}
+	}
+
+	conn.Log = log.New(&connLogWriter{b: b}, "", 0)
+
+	for _, mask := range c.Ignores {
+		if err := b.addIgnore(mask, 0); err != nil {
+			return nil, fmt.Errorf("invalid ignore: %w", err)
+		}
+	}
+
+	if err := b.loadState(c.StateFile); err != nil {
+		return nil, fmt.Errorf("could not load state: %w", err)
+	}
+
+	b.init()
+	for alias, name := range c.Aliases {
+		if err := b.addAlias(alias, name); err != nil {
+			return nil, fmt.Errorf("invalid alias: %w", err)
+		}
+	}
+
+	return b, nil
+}
+
+func (b *Bot) init() {
+	b.irc.AddCallback("PRIVMSG", b.onPrivmsg)
+	b.AddPreHook(b.checkCooldown)
+
+	cooldown := withCooldown()
+	b.createCommand("eval", true, false, b.EvalCmd, "Evaluates the given go string. Imports are automatically resolved (stdlib only). "+
+		"Prefix the code with --seed=N to make math/rand reproducible (rand becomes a seeded *rand.Rand, so rand.New "+
+		"and rand's types can't be used), --no-imports to skip resolving imports, --stdin=\"input\" to provide input "+
+		"to the program, --time to report how long it took, or --backend=gotip to run it on the development tree",
+		cooldown, withAliases("e"))
+	b.createCommand("playrun", true, false, b.PlayRun, "Runs the given play links, returning errors and output (if any). "+
+		"Gists and raw.githubusercontent.com links are also accepted. "+
+		"Prefix the links with --stdin=\"input\" to provide input to the programs, --time to report how long they "+
+		"took, or --backend=gotip to run them on the development tree", cooldown, withAliases("run"))
+	b.createCommand("play", true, false, b.PlayCmd, "Lists any errors the given play links may have. "+
+		"Prefix the links with --backend=gotip to check them against the development tree", cooldown)
+	b.createCommand("compileonly", true, false, b.CompileOnlyCmd, "Reports whether the given go string compiles, "+
+		"wrapping it as eval does, without showing its output. The playground cannot compile without running, so the "+
+		"code is still run. Prefix the code with --no-imports or --backend=gotip as with eval", cooldown)
+	b.createCommand("bench", true, false, b.BenchCmd, "Runs the BenchmarkXxx(b *testing.B) functions in the given play links, "+
+		"reporting allocations and, where the playground's fake clock allows, timings. Prefix the links with --n=N to set "+
+		"how many iterations are run", cooldown)
+	b.createCommand("vet", true, false, b.VetCmd, "Runs go vet over the given play links, and lists any issues found", cooldown)
+	b.createCommand("last", false, false, b.LastCmd, "Responds with the share link and result of the last eval run here")
+	b.createCommand("redo", true, false, b.RedoCmd, "Re-runs your last eval. Any arguments are appended to the code, "+
+		"unless they are of the form s/old/new/ (with an optional g suffix), which replaces old with new in the code", cooldown)
+	b.createCommand("source", true, false, b.SourceCmd, "Reports the size of the given play links, and links to their source", cooldown)
+	b.createCommand("share", true, false, b.ShareCmd, "Shares the given code, formatted and with imports resolved, "+
+		"and responds with a play link without running it. Gists and raw.githubusercontent.com links are shared too", cooldown)
+	b.createCommand("fmt", true, false, b.FmtCmd, "Formats the given play links, resolving imports, and responds with a paste of the result", cooldown)
+	b.createCommand("reformat", true, false, b.ReformatCmd, "Formats the given play link, resolving imports, and responds with a link to the result", cooldown)
+	b.createCommand("imports", true, false, b.ImportsCmd, "Lists the imports that would be added to or removed from the given play links when resolving imports", cooldown)
+	b.createCommand("ast", false, false, b.ASTCmd, "Parses the given go expression, statements, or file, and responds with its AST")
+	b.createCommand("adminsonline", false, false, b.AdminsOnlineCmd, "Lists which admins are currently online")
+	b.createCommand("ignores", false, false, b.IgnoresCmd, "Lists the users being ignored, and when they will be unignored")
+	b.createCommand("status", false, false, b.StatusCmd, "Reports whether or not the playground is reachable")
+	b.createCommand("uptime", false, false, b.UptimeCmd, "Reports how long the bot has been running, and connected for")
+	b.createCommand("stats", false, false, b.StatsCmd, "Reports how many times each command has been run since startup. "+
+		"Admins can use \"stats reset\" to reset the counts")
+	b.createCommand("version", false, false, b.VersionCmd, "Reports the version of the bot and its dependencies")
+	b.createCommand("ignore", false, false, b.IgnoreCmd, "Ignores commands from users matching the given mask, optionally for a duration", adminOnly())
+	b.createCommand("unignore", false, false, b.UnignoreCmd, "Stops ignoring the given mask", adminOnly())
+	b.createCommand("join", false, false, b.JoinCmd, "Joins the given channel. Use \"join --pin <#channel>\" to never part it for being idle", adminOnly())
+	b.createCommand("part", false, false, b.PartCmd, "Parts the given channel", adminOnly())
+	b.createCommand("evalcache", false, false, b.EvalCacheCmd, "Manages the cache of playground results. Use \"evalcache clear\" to empty it", adminOnly())
+	b.createCommand("caps", false, false, b.CapsCmd, "Lists the IRCv3 capabilities the server acknowledged", adminOnly())
+	b.createCommand("config", false, false, b.ConfigCmd, "Sends you the current config, with secrets redacted", adminOnly())
+	b.createCommand("reload", false, false, b.ReloadCmd, "Reloads admins, ignores, allowed channels, and command prefixes from the config file", adminOnly())
+	b.createCommand("save", false, false, b.SaveCmd, "Saves channels joined and ignores added at runtime, so they survive a restart", adminOnly())
+	b.createCommand("quit", false, false, b.QuitCmd, "Disconnects the bot", adminOnly())
+	b.createCommand("help", false, true, b.HelpCmd, "This output.")
+	b.irc.AddCallback("JOIN", b.onJoin)
+	b.irc.AddCallback("PART", b.onPart)
+	b.irc.AddCallback("KICK", b.onKick)
+	b.irc.AddCallback("INVITE", b.onInvite)
+	b.irc.AddCallback(rplMonOnline, b.onMonOnline)
+	b.irc.AddCallback(rplMonOffline, b.onMonOffline)
+	b.irc.AddCallback(rplISON, b.onISON)
+	b.irc.AddCallback(rplLoggedIn, b.onLoggedIn)
+	b.irc.AddCallback("NOTICE", b.onNickServNotice)
+	b.irc.AddConnectCallback(func(msg ircmsg.Message) {
+		infof("Connected!")
+		b.markConnected(msg)
+		b.updateCaps()
+		b.ctcpOnce.Do(b.setupCTCP)
+		if b.cfg().StartupSelfTest && b.primary == nil {
+			// Other networks share the primary's playground client, so testing it once is enough
+			b.selfTestOnce.Do(func() { go b.selfTest() })
+		}
+
+		b.resetChannels()
+		b.startPresenceTracking()
+		if b.shouldIdentify() {
+			go b.identifyThenJoin()
+		} else {
+			b.joinChannels()
+		}
+	})
+}
+
+// Run connects the bot to IRC, and blocks until it is stopped or gives up reconnecting, in which case the error
+// explains why.
+func (b *Bot) Run() error {
+	if b.cfg().AutoPartAfter > 0 {
+		go b.autoPartLoop()
+	}
+
+	if b.primary == nil {
+		if addr := b.cfg().MetricsAddr; addr != "" {
+			go b.serveMetrics(addr)
+		}
+
+		b.startWorkers()
+	}
+
+	go b.isonLoop()
+
+	infof("Connecting to %s....", b.cfg().Server)
+	if err := b.connectWithBackoff(); err != nil {
+		if !b.isStopping() {
+			return err
+		}
+
+		warnf("Stopped before connecting: %s", err)
+		return nil
+	}
+
+	b.irc.Loop()
+	return b.gaveUp
+}
+
+type (
+	ReplyFunc func(string, ...interface{}) error
+	Callback  func(ctx *CommandContext, args string, reply ReplyFunc)
+)
+
+// CommandContext describes who invoked a command, and where.
+type CommandContext struct {
+	Source  string // The full nick!user@host of the invoking user
+	Nick    string
+	UserKey string // Identifies the invoking user for anything tracked per user, see requesterKey
+	Target  string // Where replies are sent, either a channel or the invoking user's nick
+	Channel string // The channel the command was invoked in, empty for private messages
+	Message ircmsg.Message
+
+	err error // See Fail
+}
+
+// Fail records that the command failed because of err, for PostHooks. It doesn't reply, the callback still should.
+func (c *CommandContext) Fail(err error) {
+	c.err = err
+}
+
+// Command represents a single IRC command and its callback.
+type Command struct {
+	name      string
+	help      string
+	callback  Callback
+	goroutine bool // Should this callback be queued to run on a worker, rather than run inline?
+	quiet     bool // Should invocations of this command be left out of the log?
+	cooldown  bool // Must users wait CommandCooldown between invocations?
+	adminOnly bool
+	aliases   []string // Other names the command can be invoked by
+}
+
+// commandOption configures optional settings on a Command.
+type commandOption func(*Command)
+
+// adminOnly restricts the command to admins.
+func adminOnly() commandOption {
+	return func(c *Command) { c.adminOnly = true }
+}
+
+// withAliases registers the command under additional names.
+func withAliases(aliases ...string) commandOption {
+	return func(c *Command) { c.aliases = append(c.aliases, aliases...) }
+}
+
+// withCooldown makes users wait CommandCooldown between invocations of the command.
+func withCooldown() commandOption {
+	return func(c *Command) { c.cooldown = true }
+}
+
+// Name returns the name the command is invoked by.
+func (c *Command) Name() string { return c.name }
+
+func (b *Bot) createCommand(
+	name string, goroutine, quiet bool, callback Callback, help string, opts ...commandOption,
+) {
+	cmd := &Command{
+		name:      name,
+		help:      help,
+		callback:  callback,
+		goroutine: goroutine,
+		quiet:     quiet,
+	}
+
+	for _, opt := range opts {
+		opt(cmd)
+	}
+
+	b.commands[name] = cmd
+	for _, alias := range cmd.aliases {
+		b.commands[alias] = cmd
+	}
+}
+
+// addAlias registers the existing command name under alias as well.
+func (b *Bot) addAlias(alias, name string) error {
+	if _, exists := b.commands[alias]; exists {
+		return fmt.Errorf("%q is already a command", alias)
+	}
+
+	cmd, ok := b.commands[name]
+	if !ok {
+		return fmt.Errorf("%q refers to unknown command %q", alias, name)
+	}
+
+	cmd.aliases = append(cmd.aliases, alias)
+	b.commands[alias] = cmd
+	return nil
+}
+
+func (b *Bot) onPrivmsg(msg ircmsg.Message) {
+	if len(msg.Params) < 2 {
+		debugf("Ignoring PRIVMSG with too few parameters from %s: %q", msg.Prefix, msg.Params)
+		return
+	}
+
+	replyTarget := msg.Params[0]
+	sourceNick, _, _ := ircevent.SplitNUH(msg.Prefix)
+	if replyTarget == b.conn.CurrentNick() {
+		replyTarget, _, _ = ircevent.SplitNUH(msg.Prefix)
+	}
+
+	if b.isIgnored(msg.Prefix) {
+		return
+	}
+
+	if b.isReplayed(msg) {
+		b.logReplayed(msg)
+		return
+	}
+
+	c := b.cfg()
+	command, rest, ok := parseCommand(
+		msg.Params[1], b.conn.CurrentNick(), c.prefixFor(msg.Params[0]), c.PreferPrefixTrigger,
+	)
+	if !ok {
+		// Not for us, ignore it
+		return
+	}
+
+	cmd, cmdExists := b.commands[command]
+	if !cmdExists {
+		return
+	}
+
+	if b.isChannelName(msg.Params[0]) && !c.channelAllowed(msg.Params[0]) {
+		debugf("Dropping command %s from %s, %s is not in allowed_channels", cmd.name, msg.Prefix, msg.Params[0])
+		return
+	}
+
+	b.markChannelActive(msg.Params[0])
+	b.metrics.commandRun(cmd.name)
+	b.stats.commandRun(cmd.name)
+
+	if !cmd.quiet {
+		infof(
+			"Running command %s for user %s (%s) in channel %s with args %q",
+			cmd.name, msg.Prefix, requesterKey(msg), msg.Params[0], rest,
+		)
+	}
+
+	inChannel := b.isChannelName(msg.Params[0])
+	replyFunc := b.newReplyFunc(replyTarget, sourceNick, inChannel)
+
+	ctx := &CommandContext{
+		Source:  msg.Prefix,
+		Nick:    sourceNick,
+		UserKey: requesterKey(msg),
+		Target:  replyTarget,
+		Message: msg,
+	}
+
+	if inChannel {
+		ctx.Channel = msg.Params[0]
+	}
+
+	if cmd.adminOnly && !b.isAdmin(msg) {
+		replyFunc("Permission denied")
+		return
+	}
+
+	if !b.runPreHooks(ctx, cmd, rest, replyFunc) {
+		return
+	}
+
+	if cmd.goroutine {
+		err := b.enqueue(func() { b.runCommand(ctx, cmd, rest, replyFunc) })
+		if errors.Is(err, errQueueFull) {
+			replyFunc("Too many requests are queued, try again later")
+		}
+
+		if err == nil {
+			b.startCooldown(ctx, cmd)
+		}
+	} else {
+		b.startCooldown(ctx, cmd)
+		b.runCommand(ctx, cmd, rest, replyFunc)
+	}
+}
+
+// parseCommand extracts a command and its arguments from a message. Messages can either start with the command
+// prefix (`~cmd args`), or address the bot by nick (`nick: cmd args`). If a message could be parsed either way, the
+// nick form is used unless preferPrefix is set.
+func parseCommand(content, nick, prefix string, preferPrefix bool) (string, string, bool) {
+	byNick := func() (string, string, bool) {
+		if nick == "" || len(content) <= len(nick) || !strings.EqualFold(content[:len(nick)], nick) {
+			return "", "", false
+		}
+
+		// The nick must be followed by a space, or the usual `nick:` or `nick,`. Otherwise eg `goplayrun` from a
+		// user of a bot named `goplay` with the prefix `go` would be split incorrectly
+		rest := content[len(nick):]
+		switch rest[0] {
+		case ':', ',':
+			rest = rest[1:]
+		case ' ':
+		default:
+			return "", "", false
+		}
+
+		return splitCommand(strings.TrimLeft(rest, " "))
+	}
+
+	byPrefix := func() (string, string, bool) {
+		if !strings.HasPrefix(content, prefix) {
+			return "", "", false
+		}
+
+		return splitCommand(content[len(prefix):])
+	}
+
+	first, second := byNick, byPrefix
+	if preferPrefix {
+		first, second = byPrefix, byNick
+	}
+
+	if command, rest, ok := first(); ok {
+		return command, rest, true
+	}
+
+	return second()
+}
+
+// splitCommand splits s into a command name and its arguments on the first space.
+func splitCommand(s string) (string, string, bool) {
+	split := strings.SplitN(s, " ", 2)
+	if split[0] == "" {
+		return "", "", false
+	}
+
+	rest := ""
+	if len(split) > 1 {
+		rest = split[1]
+	}
+
+	return split[0], rest, true
+}
+
+// requesterKey returns a key identifying the sender of msg, for use with anything that tracks state per user.
+// If the network provided an account tag, the account is used, as it cannot be changed as easily as a nick.
+func requesterKey(msg ircmsg.Message) string {
+	if present, account := msg.GetTag("account"); present && account != "" && account != "*" {
+		return "account:" + strings.ToLower(account)
+	}
+
+	nick, _, _ := ircevent.SplitNUH(msg.Prefix)
+	return "nick:" + strings.ToLower(nick)
 }
 
-// Bot is an IRC bot and command handler
-type Bot struct {
-	config *BotConfig
-	irc    *ircevent.Connection
+// HelpCmd responds with help for commands. Only commands the requester can run are listed or described.
+func (b *Bot) HelpCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		isAdmin := b.isAdmin(ctx.Message)
+		out := []string{}
+		for name, cmd := range b.commands {
+			if name == cmd.name && (isAdmin || !cmd.adminOnly) {
+				out = append(out, name)
+			}
+		}
+
+		sort.Strings(out)
+
+		header := fmt.Sprintf("Available Commands (use %shelp $cmd for more info): ", b.cfg().prefixFor(ctx.Channel))
+		list := strings.Join(out, ", ")
+		if b.wouldTruncate(ctx, header+list) {
+			// Too long even when split across lines, so link to the full list instead
+			if link, err := b.paste(strings.Join(out, "\n")); err == nil {
+				list = link
+			}
+		}
+
+		reply("%s%s", header, list)
+		return
+	}
+
+	// Admin commands are treated as unknown to anyone else, so that their existence isn't revealed
+	cmd, ok := b.commands[args]
+	if !ok || (cmd.adminOnly && !b.isAdmin(ctx.Message)) {
+		reply("Unknown command %q", args)
+		return
+	}
+
+	if len(cmd.aliases) > 0 {
+		reply("Help for %q (aliases: %s): %s", cmd.name, strings.Join(cmd.aliases, ", "), cmd.help)
+		return
+	}
+
+	reply("Help for %q: %s", cmd.name, cmd.help)
+}
+
+var (
+	packageClauseRe = regexp.MustCompile(`^\s*package\s+\w+`)
+	mainFuncRe      = regexp.MustCompile(`\bfunc\s+main\s*\(\s*\)`)
+
+	// outputCallRe matches the obvious ways code can produce output, see HintNoOutput. It's a heuristic, so output
+	// produced some other way, such as through a helper in another package, is missed.
+	outputCallRe = regexp.MustCompile(`\bfmt\.F?Print|\bprint(?:ln)?\s*\(|\bos\.Std(?:out|err)\b|\blog\.|\bpanic\s*\(`)
+)
+
+// seededRandCode is added before code given --seed. rand.Seed has done nothing since Go 1.24, so instead rand is
+// shadowed by a seeded *rand.Rand, whose methods cover the top level math/rand functions.
+const seededRandCode = seededRandPrefix + "%d))\n_ = rand\n"
+
+// seededRandPrefix starts seededRandCode, and marks code whose math/rand use is reproducible, see cacheable.
+const seededRandPrefix = "rand := rand.New(rand.NewSource("
+
+// wrapEvalCode turns the code passed to ~eval into a complete program. Code that is already a program, or that only
+// lacks a package clause, is used as is, otherwise it is wrapped using tmpl, see eval_template.
+func wrapEvalCode(tmpl *template.Template, code string, seed int64, hasSeed bool) (string, error) {
+	isProgram := packageClauseRe.MatchString(code)
+	if isProgram || mainFuncRe.MatchString(code) {
+		if hasSeed {
+			return "", errors.New("--seed cannot be used with code that declares its own main function")
+		}
+
+		if !isProgram {
+			code = "package main\n" + code
+		}
+
+		return code, nil
+	}
+
+	if hasSeed {
+		code = fmt.Sprintf(seededRandCode, seed) + code
+	}
+
+	return executeEvalTemplate(tmpl, code)
+}
+
+// EvalCommand is the callback for the `eval` IRC command. It wraps the passed argument in some boilerplate to make it
+// valid go source, resolves any imports it can, formats it, and executes it on the go playground
+func (b *Bot) EvalCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	b.lastEvalsMu.Lock()
+	b.lastEvals[ctx.UserKey] = args
+	b.lastEvalsMu.Unlock()
+
+	flags, args, err := parseFlags(args, evalFlags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	var seed int64
+	seedStr, hasSeed := flags["seed"]
+	if hasSeed {
+		if seed, err = strconv.ParseInt(seedStr, 10, 64); err != nil {
+			reply("Invalid seed: %q is not a valid integer", seedStr)
+			return
+		}
+	}
+
+	_, noImports := flags["no-imports"]
+	stdin := flagValue(flags, "stdin")
+	backend, err := b.backendFor(flags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	if strings.TrimSpace(args) == "" {
+		reply("Cannot eval empty code")
+		return
+	}
 
-	commands     map[string]*Command
-	messageQueue chan ircmsg.Message
-}
+	if err := b.checkSourceSize(args); err != nil {
+		reply("%s", err)
+		return
+	}
 
-// New creates a new bot with the given config.
-func New(c *BotConfig) *Bot {
-	conn := &ircevent.Connection{
-		Server:          c.Server,
-		Nick:            c.Nick,
-		User:            c.User,
-		RealName:        c.RealName,
-		SASLLogin:       c.SASLUser,
-		SASLPassword:    c.SASLPassword,
-		Version:         c.VersionResponse,
-		UseTLS:          c.UseTLS,
-		UseSASL:         c.SASLPassword != "" && c.SASLUser != "",
-		EnableCTCP:      true,
-		AllowTruncation: true,
-		Log:             log.Default(),
-		Debug:           c.Debug,
+	builtUp, err := wrapEvalCode(b.evalTemplate, args, seed, hasSeed)
+	if err != nil {
+		reply("%s", err)
+		return
 	}
 
-	b := &Bot{config: c, irc: conn, commands: make(map[string]*Command)}
-	b.init()
-	return b
-}
+	share := *b.cfg().ShareOnEval
+	res, shareLink, err := b.runCode(context.Background(), builtUp, stdin, backend, share, !noImports, true)
+	if err != nil {
+		errorf("Error while sending request: %s", err)
+		ctx.Fail(err)
+		reply(fmt.Sprintf("Error occurred: %s", explainOrTrim(err.Error())))
+		return
+	}
 
-func (b *Bot) init() {
-	b.irc.AddCallback("PRIVMSG", b.onPrivmsg)
-	b.createCommand("eval", true, b.EvalCmd, "Evaluates the given go string. Imports are automatically resolved (stdlib only)")
-	b.createCommand("playrun", true, b.PlayRun, "Runs the given play link, returning errors and output (if any)")
-	b.createCommand("play", true, b.PlayCmd, "Lists any errors the given play link may have")
-	b.createCommand("help", false, b.HelpCmd, "This output.")
-	b.irc.AddConnectCallback(func(_ ircmsg.Message) {
-		log.Println("Connected!")
-		for _, ch := range b.config.JoinChannels {
-			b.irc.Join(ch)
-		}
-	})
-}
+	if len(res.Errors) != 0 {
+		// Compile failed
+		debugf("Error while running compile: %s", res.Errors)
+		reply(b.formatCompileErrors(res.Errors))
+		return
+	}
 
-// Run connects the bot to IRC, and blocks forever
-func (b *Bot) Run() {
-	log.Println("Connecting....")
-	if err := b.irc.Connect(); err != nil {
-		panic(err)
+	// No errors
+	debugf("Completed successfully: %s", shareLink)
+	out := b.formatRunResult(res)
+	if len(res.Events) == 0 && b.cfg().HintNoOutput && !outputCallRe.MatchString(args) {
+		out += " - tip: your code produced no output; did you forget fmt.Println?"
 	}
-	b.irc.Loop()
-}
 
-type (
-	ReplyFunc func(string, ...interface{}) error
-	Callback  func(args string, reply ReplyFunc)
-)
+	b.recordLast(ctx.Target, ctx.Nick, shareLink, out)
 
-// Command represents a single IRC command and its callback.
-type Command struct {
-	name      string
-	help      string
-	callback  Callback
-	goroutine bool // Should this callback be run in a goroutine?
+	header := shareLink
+	if b.showTime(flags) {
+		header = strings.TrimSpace(header + " (" + formatRunTime(res) + ")")
+	}
+
+	if header == "" {
+		reply("%s", out)
+		return
+	}
+
+	reply("%s : %s", header, out)
 }
 
-func (b *Bot) createCommand(name string, goroutine bool, callback Callback, help string) {
-	b.commands[name] = &Command{
-		name:      name,
-		help:      help,
-		callback:  callback,
-		goroutine: goroutine,
+// formatRunResult creates a single line summary of the output of a successful run, noting the exit status if it was
+// not zero. Panics are detected and summarised separately, as the first line of their output is rarely the
+// interesting part.
+func (b *Bot) formatRunResult(res *runResult) string {
+	out := b.formatRunOutput(res)
+	if res.Status != 0 {
+		out += fmt.Sprintf(" (exit %d)", res.Status)
 	}
+
+	return out
 }
 
-const minMsgLen = len("PRIVSG  :")
+func (b *Bot) formatRunOutput(res *runResult) string {
+	if len(res.Events) == 0 {
+		return "(no output)"
+	}
 
-func (b *Bot) onPrivmsg(msg ircmsg.Message) {
-	replyTarget := msg.Params[0]
-	sourceNick, _, _ := ircevent.SplitNUH(msg.Prefix)
-	if replyTarget == b.irc.CurrentNick() {
-		replyTarget, _, _ = ircevent.SplitNUH(msg.Prefix)
+	if warning := b.largeOutputWarning(res); warning != "" {
+		return warning + b.formatOutput(res)
 	}
 
-	msgContent := msg.Params[1]
-	if !strings.HasPrefix(msgContent, b.config.CommandPrefix) && !strings.HasPrefix(msgContent, b.irc.CurrentNick()) {
-		// Not for us, ignore it
-		return
+	return b.formatOutput(res)
+}
+
+// largeOutputWarning returns a warning if the output in res exceeds the configured soft limits, or an empty string
+// otherwise
+func (b *Bot) largeOutputWarning(res *runResult) string {
+	c := b.cfg()
+	size := len(joinEvents(res))
+	if len(res.Events) <= c.LargeOutputEvents && size <= c.LargeOutputBytes {
+		return ""
 	}
 
-	// its a command, lets parse things out as needed
+	return fmt.Sprintf("Large output (%d events, %d bytes)! ", len(res.Events), size)
+}
 
-	var command, rest string
-	if strings.HasPrefix(msgContent, b.irc.CurrentNick()) {
-		split := strings.SplitN(msgContent, " ", 3)
-		command = split[1]
-		if len(split) > 2 {
-			rest = split[2]
-		}
-	} else {
-		split := strings.SplitN(msgContent, " ", 2)
-		command = split[0][len(b.config.CommandPrefix):]
-		if len(split) > 1 {
-			rest = split[1]
+// formatOutput summarises the output in res on a single line.
+// If the output spans multiple lines, it is uploaded to the paste service and linked, if possible.
+func (b *Bot) formatOutput(res *runResult) string {
+	combined := strings.TrimRight(joinEvents(res), "\n")
+	if msg, traceLines, ok := extractPanic(res); ok {
+		if link, err := b.paste(combined); err == nil {
+			return fmt.Sprintf("%s (stack trace: %s)", b.extractFirstLine(msg), link)
 		}
 
+		return fmt.Sprintf("%s (%d line stack trace omitted)", b.extractFirstLine(msg), traceLines)
 	}
 
-	cmd, cmdExists := b.commands[command]
-	if !cmdExists {
-		return
+	out := b.extractFirstLine(res.Events[0].Message)
+	if res.Events[0].Kind == "stderr" {
+		out = "stderr: " + out
+	}
+	lines := strings.Count(combined, "\n") + 1
+	if len(res.Events) == 1 && lines == 1 {
+		return out
 	}
 
-	log.Printf(
-		"Running command %s for user %s in channel %s with args %q",
-		cmd.name, msg.Prefix, msg.Params[0], rest,
-	)
+	size := fmt.Sprintf("%d events, %d bytes, %d lines", len(res.Events), len(combined), lines)
+	if link, err := b.paste(combined); err == nil {
+		return fmt.Sprintf("%s (%s; full output: %s)", out, size, link)
+	}
+
+	return fmt.Sprintf("%s (First line only. %s returned)", out, size)
+}
+
+// formatCompileErrors formats the compile errors from a run for IRC. Only the first error is shown, along with a
+// count of the rest and, if possible, a link to the full text.
+func (b *Bot) formatCompileErrors(errs string) string {
+	if explanation, ok := explainError(errs); ok {
+		return explanation
+	}
 
-	replyFunc := func(s string, a ...interface{}) error {
-		if len(a) == 0 {
-			return b.irc.Privmsg(replyTarget, s)
+	var lines []string
+	for _, line := range strings.Split(errs, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
 		}
+	}
 
-		outMsg := fmt.Sprintf("(%s) %s", sourceNick, fmt.Sprintf(s, a...))
-		return b.irc.Privmsgf(replyTarget, safeTrunk(outMsg, 450-(minMsgLen+len(replyTarget)+2)))
+	switch len(lines) {
+	case 0:
+		return strings.TrimSpace(errs)
+	case 1:
+		return lines[0]
 	}
 
-	if cmd.goroutine {
-		go cmd.callback(rest, replyFunc)
-	} else {
-		cmd.callback(rest, replyFunc)
+	more := fmt.Sprintf("and %d more errors", len(lines)-1)
+	if link, err := b.paste(errs); err == nil {
+		return fmt.Sprintf("%s (%s: %s)", lines[0], more, link)
 	}
+
+	return fmt.Sprintf("%s (%s)", lines[0], more)
 }
 
-// safeTrunk trunkates a string to a valid unicode output, if possible.
-func safeTrunk(s string, length int) string {
-	if len(s) < length {
-		return s
+// joinEvents returns the output of all events in res, stdout and stderr alike, concatenated in the order the program
+// produced them. Delays between events are dropped, as the output is only ever shown or pasted as a whole.
+func joinEvents(res *runResult) string {
+	combined := &strings.Builder{}
+	for _, e := range res.Events {
+		combined.WriteString(e.Message)
 	}
 
-	for i := 0; i < 5; i++ {
-		if utf8.ValidString(s[:length-i]) {
-			return s[:length-i]
+	return combined.String()
+}
+
+// extractPanic looks for a panic in the combined output of a run. If one is found, the panic message and the number
+// of lines in the following stack trace are returned.
+func extractPanic(res *runResult) (string, int, bool) {
+	lines := strings.Split(joinEvents(res), "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "panic: ") {
+			continue
+		}
+
+		trace := lines[i+1:]
+		for j, l := range trace {
+			if strings.HasPrefix(l, "goroutine ") {
+				return line, len(trace) - j, true
+			}
 		}
 	}
 
-	return "Unable to trunkate safely."
+	return "", 0, false
 }
 
-// HelpCmd responds with help for commands.
-func (b *Bot) HelpCmd(args string, reply ReplyFunc) {
-	args = strings.TrimSpace(args)
-	if args == "" {
-		out := []string{}
-		for c := range b.commands {
-			out = append(out, c)
-		}
+// playRunFlags are the flags that can precede the links passed to ~playrun.
+var playRunFlags = []flagSpec{{name: "stdin", hasValue: true}, {name: "time"}, {name: "backend", hasValue: true}}
 
-		reply("Available Commands (use %shelp $cmd for more info): %s", b.config.CommandPrefix, strings.Join(out, ", "))
-		return
-	}
+// playFlags are the flags that can precede the links passed to ~play.
+var playFlags = []flagSpec{{name: "backend", hasValue: true}}
 
-	cmd, ok := b.commands[args]
-	if !ok {
-		reply("Unknown command %q", args)
-		return
+// evalFlags are the flags that can precede the code passed to ~eval.
+var evalFlags = []flagSpec{
+	{name: "seed", hasValue: true}, {name: "no-imports"}, {name: "stdin", hasValue: true}, {name: "time"},
+	{name: "backend", hasValue: true},
+}
+
+// showTime returns whether or not to report how long a run took, either because flags includes --time, or because
+// show_run_time is set.
+func (b *Bot) showTime(flags map[string]string) bool {
+	_, ok := flags["time"]
+	return ok || b.cfg().ShowRunTime
+}
+
+// ExtractFirstLine returns the first line of s, suppressing it entirely if it contains non-printable characters.
+func ExtractFirstLine(s string) string {
+	return sanitizeOutput(strings.SplitN(s, "\n", 2)[0], SanitizeSuppress)
+}
+
+// extractFirstLine is ExtractFirstLine, using the configured sanitize level.
+func (b *Bot) extractFirstLine(s string) string {
+	return sanitizeOutput(strings.SplitN(s, "\n", 2)[0], b.cfg().SanitizeOutput)
+}
+
+var (
+	goplaygroundURIValidRe = regexp.MustCompile(
+		`^(?:https?://)?(play\.golang\.org|go\.dev/play)/p/(` + snippetIDPattern + `(?:\.go)?)$`,
+	)
+	gistURIValidRe = regexp.MustCompile(`^(?:https?://)?gist\.github\.com/((?:[\w-]+/)?([0-9a-f]+))/?$`)
+	rawURIValidRe  = regexp.MustCompile(`^(?:https?://)?((?:raw|gist)\.githubusercontent\.com/\S+)$`)
+)
+
+// formatCode runs gofmt over the given source, additionally resolving imports if requested
+func formatCode(code []byte, doImports bool) ([]byte, error) {
+	out, err := imports.Process("prog.go", code, &imports.Options{
+		Fragment:   false,
+		AllErrors:  false,
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+		FormatOnly: !doImports,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not format / imports source: %w", err)
 	}
 
-	reply("Help for %q: %s", cmd.name, cmd.help)
+	return out, nil
 }
 
-// EvalCommand is the callback for the `eval` IRC command. It wraps the passed argument in some boilerplate to make it
-// valid go source, resolves any imports it can, formats it, and executes it on the go playground
-func (b *Bot) EvalCmd(args string, reply ReplyFunc) {
-	if strings.TrimSpace(args) == "" {
-		reply("Cannot eval empty code")
-		return
+// runCode prepares code and runs it on backend, sharing it too if doShare is set. Results are cached, so that running
+// the same code again doesn't call the playground. It is made up of prepareCode, execute, and shareCode, which can be
+// used on their own where only part of this is needed.
+func (b *Bot) runCode(
+	ctx context.Context, code string, stdin *string, backend string, doShare, doImports, doFormat bool,
+) (*runResult, string, error) {
+	codeBytes, err := b.prepareCode(ctx, code, stdin, doImports, doFormat)
+	if err != nil {
+		return nil, "", err
 	}
 
-	builtUp := fmt.Sprintf(`
-	package main
-	func main() {
-		%s
+	// The same code can behave differently on each backend, so results are cached separately for each
+	cacheKey := codeBytes
+	if backend != "" {
+		cacheKey = append([]byte(backend+"\x00"), codeBytes...)
 	}
-	`, args)
-	res, shareLink, err := b.runCode(builtUp, true, true, true)
+
+	useCache := cacheable(codeBytes, stdin)
+	if useCache {
+		if res, share, ok := b.cache.get(cacheKey, doShare); ok {
+			return res, share, nil
+		}
+	}
+
+	res, err := b.execute(ctx, codeBytes, backend)
 	if err != nil {
-		log.Print("Error while sending request: ", err)
-		reply(fmt.Sprintf("Error occurred: %s", err))
-		return
+		return nil, "", err
 	}
 
-	if len(res.Errors) != 0 {
-		// Compile failed
-		log.Print("Error while running compile: ", res.Errors)
-		reply(strings.TrimSpace(res.Errors))
-		return
+	var share, cachedShare string
+	if doShare {
+		shareCtx, cancel := context.WithTimeout(ctx, b.cfg().CompileTimeout)
+		share, err = b.shareCode(shareCtx, codeBytes)
+		cancel()
+
+		switch {
+		case err == nil:
+			cachedShare = share
+		case errors.Is(err, errShareTooLarge):
+			share = "(share skipped: snippet too large)"
+		default:
+			share = "Unable to create share link"
+			errorf("Unable to create share link: %s", err)
+		}
 	}
 
-	// No errors
-	log.Printf("Completed successfully: %s", shareLink)
-	if len(res.Events) == 0 {
-		reply("Complete, but no prints")
-	} else {
-		extraInfo := ""
-		if len(res.Events) > 1 {
-			extraInfo = fmt.Sprintf(" (First line only. %d events returned)", len(res.Events))
+	if useCache {
+		b.cache.put(cacheKey, res, cachedShare)
+	}
+
+	return res, share, nil
+}
+
+// prepareCode turns code into the program that is run: formatting it, resolving imports if requested, and adding
+// stdin. Code that checkCode rejects is refused here, before anything is sent to the playground.
+func (b *Bot) prepareCode(ctx context.Context, code string, stdin *string, doImports, doFormat bool) ([]byte, error) {
+	codeBytes := []byte(code)
+	if doImports || doFormat {
+		var err error
+		if codeBytes, err = b.format(ctx, codeBytes, doImports); err != nil {
+			return nil, err
 		}
-		reply("%s%s : %s", shareLink, extraInfo, ExtractFirstLine(res.Events[0].Message))
 	}
+
+	// Checked before adding stdin, whose file is trusted, so that the user's code is checked as they wrote it
+	c := b.cfg()
+	if err := checkCode(codeBytes, c.DisallowedImports, c.RejectEmptyLoops); err != nil {
+		return nil, err
+	}
+
+	if stdin != nil {
+		codeBytes = []byte(withStdin(string(codeBytes), *stdin))
+	}
+
+	return codeBytes, nil
 }
 
-func ExtractFirstLine(s string) string {
-	trimmed := strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+// execute compiles and runs code on backend, within CompileTimeout. Unlike compile, it respects the circuit breaker,
+// and records the outcome in it and the metrics.
+func (b *Bot) execute(ctx context.Context, code []byte, backend string) (*runResult, error) {
+	if !b.breaker.allow() {
+		return nil, errPlaygroundDown
+	}
+
+	timeout := b.cfg().CompileTimeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var res *runResult
+	start := time.Now()
+	err := b.callPlayground(ctx, func() error {
+		var err error
+		compileStart := time.Now()
+		res, err = b.compile(ctx, code, backend)
+		if err == nil {
+			res.Elapsed = time.Since(compileStart)
+		}
+
+		return err
+	})
 
-	hasNonPrintables := false
-	for _, c := range trimmed {
-		if !unicode.IsPrint(c) {
-			hasNonPrintables = true
-			break
+	if err != nil {
+		b.metrics.compileDone(compileRequestError, time.Since(start))
+		b.breaker.failure()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("compile timed out after %s", timeout)
 		}
+
+		return nil, fmt.Errorf("error from goplay: %w", err)
 	}
 
-	if hasNonPrintables {
-		return "Output suppressed, non-printable characters detected."
+	result := compileOK
+	if res.Errors != "" {
+		result = compileFailed
 	}
 
-	return strings.ReplaceAll(trimmed, "\x07", "")
+	b.metrics.compileDone(result, time.Since(start))
+	b.breaker.success()
+	return res, nil
 }
 
-var (
-	snippetValidRe         = regexp.MustCompile(`[a-zA-Z0-9]{8,}(?:\.go)?`)
-	goplaygroundURIValidRe = regexp.MustCompile(`^(?:https?://)?play.golang.org/p/([a-zA-Z0-9]{8,}(?:\.go)?)$`)
-)
+const defaultPlayground = "https://play.golang.org"
+
+// snippetSource is somewhere the code for ~playrun and friends can be downloaded from.
+type snippetSource struct {
+	id  string // identifies the snippet in replies
+	url string
 
-func snippetIsValid(snippet string) bool {
-	return snippetValidRe.MatchString(snippet)
+	// Code from outside the playground may be anything, so is checked to be Go before use
+	external bool
 }
 
-func (b *Bot) runCode(code string, doShare, doImports, doFormat bool) (*goplay.Response, string, error) {
-	codeBytes := []byte(code)
-	var err error
-	if doImports || doFormat {
-		codeBytes, err = imports.Process("prog.go", codeBytes, &imports.Options{
-			Fragment:   false,
-			AllErrors:  false,
-			Comments:   true,
-			TabIndent:  true,
-			TabWidth:   8,
-			FormatOnly: !doImports,
-		})
+// parseSnippetSource parses a playground link or bare snippet ID, a gist link, or a raw GitHub link.
+func parseSnippetSource(source string) (snippetSource, error) {
+	if matches := goplaygroundURIValidRe.FindStringSubmatch(source); matches != nil {
+		id := strings.TrimSuffix(matches[2], ".go")
+		return snippetSource{id: id, url: fmt.Sprintf("https://%s/p/%s.go", matches[1], id)}, nil
 	}
 
-	if err != nil {
-		return nil, "", fmt.Errorf("could not format / imports source: %w", err)
+	if matches := gistURIValidRe.FindStringSubmatch(source); matches != nil {
+		// GitHub redirects this to the raw content of the gist's first file
+		return snippetSource{id: matches[2], url: "https://gist.github.com/" + matches[1] + "/raw", external: true}, nil
 	}
 
-	var share string
-	if doShare {
-		share = "Unable to create share link"
-		s, err := goplay.DefaultClient.Share(bytes.NewReader(codeBytes))
-		if err == nil {
-			share = s
-		} else {
-			log.Println(err)
-		}
+	if matches := rawURIValidRe.FindStringSubmatch(source); matches != nil {
+		return snippetSource{id: path.Base(matches[1]), url: "https://" + matches[1], external: true}, nil
 	}
 
-	res, err := goplay.DefaultClient.Compile(bytes.NewReader(codeBytes))
-	if err != nil {
-		return nil, "", fmt.Errorf("error from goplay: %w", err)
+	if bareSnippetIDRe.MatchString(source) {
+		id := strings.TrimSuffix(source, ".go")
+		return snippetSource{id: id, url: fmt.Sprintf("%s/p/%s.go", defaultPlayground, id)}, nil
 	}
 
-	return res, share, nil
+	return snippetSource{}, errors.New("invalid snippet")
+}
+
+var errSnippetNotFound = errors.New("snippet does not exist")
+
+// sourceTooLargeError is returned for code larger than MaxSourceBytes.
+type sourceTooLargeError struct {
+	size int // -1 if the full size is not known
+	max  int
 }
 
-func extractPlaySnippetID(source string) (string, error) {
-	matches := goplaygroundURIValidRe.FindStringSubmatch(source)
-	if matches != nil {
-		return matches[1], nil
+func (e *sourceTooLargeError) Error() string {
+	if e.size < 0 {
+		return fmt.Sprintf("source too large (over %d bytes)", e.max)
 	}
 
-	if snippetIsValid(source) {
-		return source, nil
+	return fmt.Sprintf("source too large (%d bytes, max %d)", e.size, e.max)
+}
+
+// checkSourceSize returns a *sourceTooLargeError if code is larger than MaxSourceBytes.
+func (b *Bot) checkSourceSize(code string) error {
+	if limit := b.cfg().MaxSourceBytes; len(code) > limit {
+		return &sourceTooLargeError{size: len(code), max: limit}
 	}
 
-	return "", errors.New("invalid snippet")
+	return nil
 }
 
-func downloadPlaySnippet(source string) (string, error) {
-	id, err := extractPlaySnippetID(source)
+// downloadSnippet downloads the code for source, which is anything accepted by parseSnippetSource. Downloads larger
+// than MaxSourceBytes are refused.
+func (b *Bot) downloadSnippet(ctx context.Context, source string) (string, error) {
+	src, err := parseSnippetSource(source)
 	if err != nil {
 		return "", err
 	}
 
-	if !strings.HasSuffix(id, ".go") {
-		id = id + ".go"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.url, nil)
+	if err != nil {
+		return "", err
 	}
-	res, err := http.Get(fmt.Sprintf("%s/p/%s", "https://play.golang.org", id))
+
+	res, err := b.httpClient.Do(req)
 	if err != nil {
-		log.Print(err)
+		errorf("Unable to download snippet %s: %s", src.id, err)
 		return "", err
 	}
 
+	defer res.Body.Close()
+
 	switch res.StatusCode {
-	case 200:
-	case 404:
-		return "", errors.New("snippet does not exist")
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", errSnippetNotFound
 	default:
-		return "", errors.New("unknown error")
+		errorf("Unexpected response downloading snippet %s: %s", src.id, res.Status)
+		return "", fmt.Errorf("unexpected response: %s", res.Status)
 	}
 
-	defer res.Body.Close()
-	data, err := ioutil.ReadAll(res.Body)
+	maxSize := b.cfg().MaxSourceBytes
+	if res.ContentLength > int64(maxSize) {
+		return "", &sourceTooLargeError{size: int(res.ContentLength), max: maxSize}
+	}
+
+	// The length isn't always known up front, so the limit is enforced while reading too
+	data, err := io.ReadAll(io.LimitReader(res.Body, int64(maxSize)+1))
 	if err != nil {
 		return "", err
 	}
 
+	if len(data) > maxSize {
+		return "", &sourceTooLargeError{size: -1, max: maxSize}
+	}
+
+	if src.external {
+		if err := checkGoSource(data); err != nil {
+			return "", err
+		}
+	}
+
 	return string(data), nil
 }
 
-// PlayRun runs the given go playground link and responds with either the errors, its the callback for the
-// ~runplay command
-func (b *Bot) PlayRun(args string, reply ReplyFunc) {
-	if args == "" {
-		reply("Cannot parse an empty link / URL")
+// checkGoSource returns an error if data does not look like a Go source file.
+func checkGoSource(data []byte) error {
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) != -1 {
+		return errors.New("not a text file")
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "", data, parser.PackageClauseOnly); err != nil {
+		return errors.New("not a Go source file")
+	}
+
+	return nil
+}
+
+// PlayRun runs the given go playground links and responds with either the errors or output of each, its the callback
+// for the ~playrun command
+func (b *Bot) PlayRun(ctx *CommandContext, args string, reply ReplyFunc) {
+	flags, args, err := parseFlags(args, playRunFlags)
+	if err != nil {
+		reply("%s", err)
 		return
 	}
 
-	code, err := downloadPlaySnippet(args)
+	stdin := flagValue(flags, "stdin")
+	showTime := b.showTime(flags)
+	backend, err := b.backendFor(flags)
 	if err != nil {
-		log.Print(err)
-		reply("Unable to download snippet: %q", err)
+		reply("%s", err)
 		return
 	}
 
-	runRes, _, err := b.runCode(code, false, false, false)
+	share := *b.cfg().ShareOnPlayrun
+	b.forEachSnippet(args, reply, func(code string) string {
+		runRes, shareLink, err := b.runCode(context.Background(), code, stdin, backend, share, false, false)
+		if err != nil {
+			errorf("Unable to start compile: %s", err)
+			ctx.Fail(err)
+			return "Unable to start compile: " + explainOrTrim(err.Error())
+		}
+
+		if len(runRes.Errors) != 0 {
+			// Compile failed
+			debugf("Error while running compile: %s", runRes.Errors)
+			return "Compile failed! " + b.formatCompileErrors(runRes.Errors)
+		}
+
+		// No errors
+		status := "Complete"
+		if showTime {
+			status = fmt.Sprintf("Complete (%s)", formatRunTime(runRes))
+		}
+
+		if shareLink != "" {
+			return fmt.Sprintf("%s: %s : %s", status, shareLink, b.formatRunResult(runRes))
+		}
+
+		return status + ": " + b.formatRunResult(runRes)
+	})
+}
+
+// PlayCmd is the callback for the ~play IRC command, and responds with any errors the playground snippets have
+func (b *Bot) PlayCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	flags, args, err := parseFlags(args, playFlags)
 	if err != nil {
-		log.Println("Unable to start compile", err)
-		reply("Unable to start compile: %s", err)
+		reply("%s", err)
 		return
 	}
 
-	if len(runRes.Errors) != 0 {
-		// Compile failed
-		log.Print("Error while running compile: ", runRes.Errors)
-		reply(fmt.Sprintf("Compile failed! %s", strings.TrimSpace(runRes.Errors)))
+	backend, err := b.backendFor(flags)
+	if err != nil {
+		reply("%s", err)
 		return
 	}
 
-	// No errors
-	if len(runRes.Events) == 0 {
-		reply("Complete, but no prints")
-	} else {
-		reply("Complete: %s", ExtractFirstLine(runRes.Events[0].Message))
+	b.forEachSnippet(args, reply, func(code string) string {
+		runRes, _, err := b.runCode(context.Background(), code, nil, backend, false, false, false)
+		if err != nil {
+			errorf("Unable to start compile: %s", err)
+			ctx.Fail(err)
+			return "Unable to start compile: " + explainOrTrim(err.Error())
+		}
+
+		if len(runRes.Errors) != 0 {
+			// Compile failed
+			debugf("Error while running compile: %s", runRes.Errors)
+			return "Errors: " + b.formatCompileErrors(runRes.Errors)
+		}
+
+		return "No errors in file"
+	})
+}
+
+// forEachSnippet downloads each of the whitespace separated snippets in args, up to MaxSnippets, and replies with
+// the result of calling fn on its code. When multiple snippets are given, each reply is prefixed with its ID
+func (b *Bot) forEachSnippet(args string, reply ReplyFunc, fn func(code string) string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		reply("Cannot parse an empty link / URL")
+		return
+	}
+
+	var snippets, ids []string
+	invalid := 0
+	for _, f := range fields {
+		src, err := parseSnippetSource(f)
+		if err != nil {
+			invalid++
+			continue
+		}
+
+		snippets = append(snippets, f)
+		ids = append(ids, src.id)
+	}
+
+	if len(snippets) == 0 {
+		reply("Unable to get snippet: invalid snippet")
+		return
+	}
+
+	maxSnippets := b.cfg().MaxSnippets
+	overLimit := 0
+	if len(snippets) > maxSnippets {
+		overLimit = len(snippets) - maxSnippets
+		snippets, ids = snippets[:maxSnippets], ids[:maxSnippets]
+	}
+
+	for i, snippet := range snippets {
+		var res string
+		if code, err := b.downloadSnippet(context.Background(), snippet); errors.Is(err, errSnippetNotFound) {
+			res = "Snippet does not exist"
+		} else if err != nil {
+			warnf("Unable to get snippet: %s", err)
+			res = fmt.Sprintf("Unable to get snippet: %s", err)
+		} else {
+			res = fn(code)
+		}
+
+		if len(fields) > 1 {
+			res = ids[i] + ": " + res
+		}
+
+		reply("%s", res)
+	}
+
+	if invalid > 0 {
+		reply("skipped %d invalid arguments", invalid)
 	}
+
+	if overLimit > 0 {
+		reply("skipped %d snippets over the limit of %d", overLimit, maxSnippets)
+	}
+}
+
+// SourceCmd is the callback for the ~source IRC command. It reports the size of the given snippets as the bot sees
+// them, and links to a paste of their source
+func (b *Bot) SourceCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	b.forEachSnippet(args, reply, func(code string) string {
+		size := fmt.Sprintf("%d bytes, %d lines", len(code), strings.Count(strings.TrimRight(code, "\n"), "\n")+1)
+		link, err := b.paste(code)
+		if errors.Is(err, errNoPaster) {
+			return size
+		} else if err != nil {
+			return size + " (unable to paste source)"
+		}
+
+		return fmt.Sprintf("%s: %s", size, link)
+	})
+}
+
+// FmtCmd is the callback for the ~fmt IRC command. It formats the given snippets, resolving imports, and pastes the
+// results without compiling them
+func (b *Bot) FmtCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	b.forEachSnippet(args, reply, func(code string) string {
+		formatted, err := b.format(context.Background(), []byte(code), true)
+		if err != nil {
+			return "Unable to format snippet: " + strings.TrimSpace(err.Error())
+		}
+
+		link, err := b.paste(string(formatted))
+		if err != nil {
+			return "Unable to paste formatted snippet: " + err.Error()
+		}
+
+		return "Formatted: " + link
+	})
 }
 
-// PlayCmd is the callback for the ~play IRC command, and responds with any errors the playground code has
-func (b *Bot) PlayCmd(args string, reply ReplyFunc) {
+// ReformatCmd is the callback for the ~reformat IRC command. It formats the given playground snippet and shares the
+// cleaned up version, without compiling it
+func (b *Bot) ReformatCmd(ctx *CommandContext, args string, reply ReplyFunc) {
 	if args == "" {
 		reply("Cannot parse an empty link / URL")
 		return
 	}
 
-	code, err := downloadPlaySnippet(args)
+	code, err := b.downloadSnippet(context.Background(), args)
 	if err != nil {
-		log.Print(err)
+		warnf("Unable to get snippet: %s", err)
+		ctx.Fail(err)
 		reply("Unable to get snippet: %s", err)
 		return
 	}
 
-	runRes, _, err := b.runCode(code, false, false, false)
+	formatted, err := b.format(context.Background(), []byte(code), true)
 	if err != nil {
-		log.Println("Unable to start compile", err)
-		reply("Unable to start compile: %s", err)
+		reply("Unable to format snippet: %s", err)
 		return
 	}
 
-	if len(runRes.Errors) != 0 {
-		// Compile failed
-		log.Print("Error while running compile: ", runRes.Errors)
-		reply(fmt.Sprintf("Errors: %s", strings.TrimSpace(runRes.Errors)))
+	if string(formatted) == code {
+		reply("Snippet is already formatted")
+		return
+	}
+
+	shareCtx, cancel := context.WithTimeout(context.Background(), b.cfg().CompileTimeout)
+	defer cancel()
+
+	link, err := b.shareCode(shareCtx, formatted)
+	if err != nil {
+		errorf("Unable to share formatted snippet: %s", err)
+		ctx.Fail(err)
+		reply("Unable to create share link: %s", err)
 		return
 	}
 
-	reply("No errors in file")
+	reply("Formatted: %s", link)
 }
@@ -0,0 +1,684 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+	"github.com/haya14busa/goplay"
+)
+
+// newTestBot creates a bot that is never connected, with replies unthrottled and state kept out of the working
+// directory. modify, if not nil, can change the config before the bot is created.
+func newTestBot(t *testing.T, modify func(c *BotConfig)) *Bot {
+	t.Helper()
+
+	c := &BotConfig{
+		Nick:              "goplay",
+		Server:            "irc.example.com:6697",
+		CommandPrefix:     "~",
+		MessagesPerSecond: -1,
+		StateFile:         filepath.Join(t.TempDir(), "state.toml"),
+	}
+
+	if modify != nil {
+		modify(c)
+	}
+
+	b, err := New(c)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return b
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		prefix       string
+		preferPrefix bool
+		wantCmd      string
+		wantRest     string
+		wantOK       bool
+	}{
+		{name: "prefix", content: "~eval 1", prefix: "~", wantCmd: "eval", wantRest: "1", wantOK: true},
+		{name: "prefix without args", content: "~help", prefix: "~", wantCmd: "help", wantOK: true},
+		{name: "prefix with empty args", content: "~help ", prefix: "~", wantCmd: "help", wantOK: true},
+		{name: "long prefix", content: "go!eval 1", prefix: "go!", wantCmd: "eval", wantRest: "1", wantOK: true},
+		{name: "bare prefix", content: "~", prefix: "~"},
+		{name: "prefix then space", content: "~ eval", prefix: "~"},
+		{name: "no trigger", content: "eval 1", prefix: "~"},
+		{name: "nick colon", content: "goplay: eval 1", prefix: "~", wantCmd: "eval", wantRest: "1", wantOK: true},
+		{name: "nick comma", content: "goplay, eval 1", prefix: "~", wantCmd: "eval", wantRest: "1", wantOK: true},
+		{name: "nick space", content: "goplay eval 1", prefix: "~", wantCmd: "eval", wantRest: "1", wantOK: true},
+		{name: "nick without space", content: "goplay:eval", prefix: "~", wantCmd: "eval", wantOK: true},
+		{name: "nick any case", content: "GoPlay: help", prefix: "~", wantCmd: "help", wantOK: true},
+		{name: "nick alone", content: "goplay:", prefix: "~"},
+		{
+			name: "nick as the start of a word", content: "goplayrun x", prefix: "go",
+			wantCmd: "playrun", wantRest: "x", wantOK: true,
+		},
+		{name: "longer nick", content: "goplaybot: help", prefix: "~"},
+		{
+			name: "nick preferred over prefix", content: "goplay: help", prefix: "go",
+			wantCmd: "help", wantOK: true,
+		},
+		{
+			name: "prefix preferred over nick", content: "goplay: help", prefix: "go", preferPrefix: true,
+			wantCmd: "play:", wantRest: "help", wantOK: true,
+		},
+		{
+			name: "flags are left in the args", content: "~eval --seed=1 --time fmt.Println(1)", prefix: "~",
+			wantCmd: "eval", wantRest: "--seed=1 --time fmt.Println(1)", wantOK: true,
+		},
+		{
+			name: "args keep their spacing", content: "~eval  a  b ", prefix: "~",
+			wantCmd: "eval", wantRest: " a  b ", wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, rest, ok := parseCommand(tt.content, "goplay", tt.prefix, tt.preferPrefix)
+			if cmd != tt.wantCmd || rest != tt.wantRest || ok != tt.wantOK {
+				t.Errorf(
+					"parseCommand(%q) = %q, %q, %t; want %q, %q, %t",
+					tt.content, cmd, rest, ok, tt.wantCmd, tt.wantRest, tt.wantOK,
+				)
+			}
+		})
+	}
+}
+
+func TestParseCommandFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantFlags map[string]string
+		wantRest  string
+		wantErr   bool
+	}{
+		{name: "no flags", content: "~eval 1", wantFlags: map[string]string{}, wantRest: "1"},
+		{name: "empty args", content: "~eval", wantFlags: map[string]string{}, wantRest: ""},
+		{
+			name: "value and bool flags", content: "~eval --seed=3 --time 1",
+			wantFlags: map[string]string{"seed": "3", "time": ""}, wantRest: "1",
+		},
+		{
+			name: "quoted value", content: `~eval --stdin="a b" 1`,
+			wantFlags: map[string]string{"stdin": "a b"}, wantRest: "1",
+		},
+		{name: "flags only", content: "~eval --time", wantFlags: map[string]string{"time": ""}, wantRest: ""},
+		{name: "unknown flag", content: "~eval --nope 1", wantErr: true},
+		{name: "missing value", content: "~eval --seed 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args, ok := parseCommand(tt.content, "goplay", "~", false)
+			if !ok || cmd != "eval" {
+				t.Fatalf("parseCommand(%q) = %q, %t; want eval", tt.content, cmd, ok)
+			}
+
+			flags, rest, err := parseFlags(args, evalFlags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags(%q) error = %v, want error %t", args, err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if rest != tt.wantRest {
+				t.Errorf("parseFlags(%q) rest = %q, want %q", args, rest, tt.wantRest)
+			}
+
+			if len(flags) != len(tt.wantFlags) {
+				t.Errorf("parseFlags(%q) flags = %v, want %v", args, flags, tt.wantFlags)
+			}
+
+			for name, want := range tt.wantFlags {
+				if got, ok := flags[name]; !ok || got != want {
+					t.Errorf("parseFlags(%q) flag %s = %q, want %q", args, name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	b := newTestBot(t, func(c *BotConfig) {
+		c.Aliases = map[string]string{"r": "playrun"}
+	})
+
+	tests := []struct {
+		alias string
+		want  string
+	}{
+		{"e", "eval"},
+		{"run", "playrun"},
+		{"r", "playrun"},
+		{"eval", "eval"},
+	}
+
+	for _, tt := range tests {
+		cmd, ok := b.commands[tt.alias]
+		if !ok {
+			t.Errorf("command %q not registered", tt.alias)
+			continue
+		}
+
+		if cmd.name != tt.want {
+			t.Errorf("command %q resolves to %q, want %q", tt.alias, cmd.name, tt.want)
+		}
+	}
+
+	if err := b.addAlias("help", "eval"); err == nil {
+		t.Error("addAlias() shadowing an existing command succeeded, want error")
+	}
+
+	if err := b.addAlias("x", "nonexistent"); err == nil {
+		t.Error("addAlias() for an unknown command succeeded, want error")
+	}
+}
+
+// newDispatchBot creates a test bot with a fake connection, and an echo command that replies with its arguments.
+func newDispatchBot(t *testing.T, modify func(c *BotConfig), opts ...commandOption) (*Bot, *fakeConn) {
+	t.Helper()
+
+	b := newTestBot(t, modify)
+	f := useFakeConn(b)
+	b.createCommand("echo", false, false, func(_ *CommandContext, args string, reply ReplyFunc) {
+		reply("echo: %s", args)
+	}, "Replies with its arguments", opts...)
+
+	return b, f
+}
+
+func assertPrivmsgs(t *testing.T, f *fakeConn, want ...fakePrivmsg) {
+	t.Helper()
+
+	got := f.takePrivmsgs()
+	if len(got) != len(want) {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOnPrivmsgReplies(t *testing.T) {
+	b, f := newDispatchBot(t, nil)
+
+	tests := []struct {
+		name   string
+		target string
+		text   string
+		want   []fakePrivmsg
+	}{
+		{
+			name: "channel", target: "#go", text: "~echo hi",
+			want: []fakePrivmsg{{"#go", "(user) echo: hi"}},
+		},
+		{
+			name: "addressed by nick", target: "#go", text: "goplay: echo hi",
+			want: []fakePrivmsg{{"#go", "(user) echo: hi"}},
+		},
+		{
+			name: "private message", target: "goplay", text: "~echo hi",
+			want: []fakePrivmsg{{"user", "echo: hi"}},
+		},
+		{name: "unknown command", target: "#go", text: "~nonexistent hi"},
+		{name: "not a command", target: "#go", text: "echo hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.onPrivmsg(privmsgFrom("user!u@host", tt.target, tt.text))
+			assertPrivmsgs(t, f, tt.want...)
+		})
+	}
+}
+
+func TestOnPrivmsgQueued(t *testing.T) {
+	b := newTestBot(t, nil)
+	f := useFakeConn(b)
+	b.createCommand("slow", true, false, func(_ *CommandContext, _ string, reply ReplyFunc) {
+		reply("done")
+	}, "Runs on a worker")
+
+	b.startWorkers()
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~slow"))
+	b.inFlight.Wait()
+
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) done"})
+}
+
+func TestOnPrivmsgIgnores(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) {
+		c.Ignores = []string{"troll!*@*"}
+	})
+
+	b.onPrivmsg(privmsgFrom("troll!t@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f)
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: hi"})
+
+	if err := b.addIgnore("user!*@*", 0); err != nil {
+		t.Fatalf("addIgnore() error = %v", err)
+	}
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f)
+}
+
+func TestOnPrivmsgCooldown(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) {
+		c.Admins = []string{"admin!*@*"}
+		c.CommandCooldown = time.Minute
+	}, withCooldown())
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo 1"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: 1"})
+
+	// The user is told to wait once, and further attempts in the same window are dropped
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo 2"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) Please wait 1m0s before using ~echo again"})
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo 3"))
+	assertPrivmsgs(t, f)
+
+	// Cooldowns are per user
+	b.onPrivmsg(privmsgFrom("other!o@host", "#go", "~echo 4"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(other) echo: 4"})
+
+	// and admins are exempt
+	for i := 0; i < 2; i++ {
+		b.onPrivmsg(privmsgFrom("admin!a@host", "#go", "~echo 5"))
+		assertPrivmsgs(t, f, fakePrivmsg{"#go", "(admin) echo: 5"})
+	}
+}
+
+func TestCooldownFollowsConfig(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) { c.CommandCooldown = time.Minute }, withCooldown())
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo 1"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: 1"})
+
+	c := *b.cfg()
+	c.CommandCooldown = -1
+	b.configMu.Lock()
+	b.config = &c
+	b.configMu.Unlock()
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo 2"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: 2"})
+}
+
+func TestCooldownNotStartedWhenQueueFull(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) {
+		c.CommandCooldown = time.Minute
+		c.QueueSize = 1
+	})
+
+	b.createCommand("slow", true, false, func(_ *CommandContext, _ string, reply ReplyFunc) {
+		reply("done")
+	}, "Runs on a worker", withCooldown())
+
+	// No workers are running, so the first command fills the queue
+	b.onPrivmsg(privmsgFrom("other!o@host", "#go", "~slow"))
+	assertPrivmsgs(t, f)
+
+	for i := 0; i < 2; i++ {
+		b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~slow"))
+		assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) Too many requests are queued, try again later"})
+	}
+}
+
+func TestOnPrivmsgAdminOnly(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) {
+		c.Admins = []string{"admin!*@*"}
+	}, adminOnly())
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) Permission denied"})
+
+	b.onPrivmsg(privmsgFrom("admin!a@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(admin) echo: hi"})
+}
+
+func TestRequesterKey(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{name: "no tags", want: "nick:user"},
+		{name: "account", tags: map[string]string{"account": "Someone"}, want: "account:someone"},
+		{name: "logged out", tags: map[string]string{"account": "*"}, want: "nick:user"},
+		{name: "empty account", tags: map[string]string{"account": ""}, want: "nick:user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := ircmsg.MakeMessage(tt.tags, "User!u@host", "PRIVMSG", "#go", "~eval 1")
+			if got := requesterKey(msg); got != tt.want {
+				t.Errorf("requesterKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCooldownKeying(t *testing.T) {
+	account := func(name string) map[string]string { return map[string]string{"account": name} }
+
+	tests := []struct {
+		name      string
+		first     ircmsg.Message
+		second    ircmsg.Message
+		wantShare bool // whether the second message is subject to the first's cooldown
+	}{
+		{
+			name:      "same account, different nicks",
+			first:     ircmsg.MakeMessage(account("acct"), "one!u@host", "PRIVMSG", "#go", "~echo"),
+			second:    ircmsg.MakeMessage(account("acct"), "two!u@host", "PRIVMSG", "#go", "~echo"),
+			wantShare: true,
+		},
+		{
+			name:   "different accounts, same nick",
+			first:  ircmsg.MakeMessage(account("a"), "one!u@host", "PRIVMSG", "#go", "~echo"),
+			second: ircmsg.MakeMessage(account("b"), "one!u@host", "PRIVMSG", "#go", "~echo"),
+		},
+		{
+			name:      "no accounts, same nick",
+			first:     privmsgFrom("one!u@host", "#go", "~echo"),
+			second:    privmsgFrom("one!other@elsewhere", "#go", "~echo"),
+			wantShare: true,
+		},
+		{
+			name:   "no accounts, different nicks",
+			first:  privmsgFrom("one!u@host", "#go", "~echo"),
+			second: privmsgFrom("two!u@host", "#go", "~echo"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, f := newDispatchBot(t, func(c *BotConfig) { c.CommandCooldown = time.Minute }, withCooldown())
+			b.onPrivmsg(tt.first)
+			b.onPrivmsg(tt.second)
+
+			sent := f.takePrivmsgs()
+			if len(sent) != 2 {
+				t.Fatalf("sent %q, want 2 messages", sent)
+			}
+
+			waited := strings.Contains(sent[1].text, "Please wait")
+			if waited != tt.wantShare {
+				t.Errorf("second reply = %q, want cooldown %t", sent[1].text, tt.wantShare)
+			}
+		})
+	}
+}
+
+func TestTriggerPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		preferPrefix bool
+		target       string
+		text         string
+		want         string // empty if nothing should be run
+	}{
+		{name: "nick only", text: "goplay: echo hi", want: "echo: hi"},
+		{name: "prefix only", text: "goecho hi", want: "echo: hi"},
+		{name: "both, nick preferred", text: "goplay echo", want: "echo: "},
+		{name: "both, prefix preferred", preferPrefix: true, text: "goplay echo", want: "play: echo"},
+		{name: "channel override", target: "#other", text: "!echo hi", want: "echo: hi"},
+		{name: "channel override replaces prefix", target: "#other", text: "goecho hi"},
+		{name: "nick still works with override", target: "#other", text: "goplay: echo hi", want: "echo: hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, f := newDispatchBot(t, func(c *BotConfig) {
+				c.CommandPrefix = "go"
+				c.ChannelPrefixes = map[string]string{"#other": "!"}
+				c.PreferPrefixTrigger = tt.preferPrefix
+			})
+
+			b.createCommand("play", false, false, func(_ *CommandContext, args string, reply ReplyFunc) {
+				reply("play: %s", args)
+			}, "Replies with its arguments")
+
+			target := tt.target
+			if target == "" {
+				target = "#go"
+			}
+
+			b.onPrivmsg(privmsgFrom("user!u@host", target, tt.text))
+			if tt.want == "" {
+				assertPrivmsgs(t, f)
+				return
+			}
+
+			assertPrivmsgs(t, f, fakePrivmsg{target, "(user) " + tt.want})
+		})
+	}
+}
+
+// stdout returns a stdout event with the given message.
+func stdout(msg string) *goplay.Event { return &goplay.Event{Message: msg, Kind: "stdout"} }
+
+// stderr returns a stderr event with the given message.
+func stderr(msg string) *goplay.Event { return &goplay.Event{Message: msg, Kind: "stderr"} }
+
+func TestLargeOutputWarning(t *testing.T) {
+	b := newTestBot(t, func(c *BotConfig) {
+		c.LargeOutputEvents = 2
+		c.LargeOutputBytes = 10
+	})
+
+	tests := []struct {
+		name   string
+		events []*goplay.Event
+		want   string
+	}{
+		{name: "no output", want: ""},
+		{name: "at both limits", events: []*goplay.Event{stdout("12345"), stdout("67890")}, want: ""},
+		{
+			name: "one event over", events: []*goplay.Event{stdout("1"), stdout("2"), stdout("3")},
+			want: "Large output (3 events, 3 bytes)! ",
+		},
+		{
+			name: "one byte over", events: []*goplay.Event{stdout("12345678901")},
+			want: "Large output (1 events, 11 bytes)! ",
+		},
+		{
+			name: "bytes across events", events: []*goplay.Event{stdout("123456"), stderr("78901")},
+			want: "Large output (2 events, 11 bytes)! ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &runResult{Response: goplay.Response{Events: tt.events}}
+			if got := b.largeOutputWarning(res); got != tt.want {
+				t.Errorf("largeOutputWarning() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatOutputSummary(t *testing.T) {
+	b := newTestBot(t, nil)
+	res := &runResult{Response: goplay.Response{Events: []*goplay.Event{stdout("a\nb\n"), stderr("c")}}}
+
+	if got, want := b.formatOutput(res), "a (First line only. 2 events, 5 bytes, 3 lines returned)"; got != want {
+		t.Errorf("formatOutput() without a paste service = %q, want %q", got, want)
+	}
+
+	b.paster = &fakePaster{}
+	want := "a (2 events, 5 bytes, 3 lines; full output: https://paste.example.com/1)"
+	if got := b.formatOutput(res); got != want {
+		t.Errorf("formatOutput() with a paste service = %q, want %q", got, want)
+	}
+}
+
+func TestFormatRunOutputNotExplained(t *testing.T) {
+	b := newTestBot(t, nil)
+	for _, out := range []string{
+		"cannot find package \"foo\"",
+		"program is too long",
+		"dial tcp: lookup example.com: no such host",
+	} {
+		res := &runResult{Response: goplay.Response{Events: []*goplay.Event{stdout(out)}}}
+		if got := b.formatRunOutput(res); got != out {
+			t.Errorf("formatRunOutput() for output %q = %q, want it unchanged", out, got)
+		}
+	}
+}
+
+func TestParseSnippetSource(t *testing.T) {
+	golang := snippetSource{id: "abcdefgh12", url: "https://play.golang.org/p/abcdefgh12.go"}
+	godev := snippetSource{id: "abcdefgh12", url: "https://go.dev/play/p/abcdefgh12.go"}
+	bare := snippetSource{id: "abcdefgh12", url: defaultPlayground + "/p/abcdefgh12.go"}
+
+	tests := []struct {
+		source  string
+		want    snippetSource
+		wantErr bool
+	}{
+		{source: "https://play.golang.org/p/abcdefgh12", want: golang},
+		{source: "https://play.golang.org/p/abcdefgh12.go", want: golang},
+		{source: "play.golang.org/p/abcdefgh12", want: golang},
+		{source: "http://play.golang.org/p/abcdefgh12.go", want: golang},
+		{source: "https://go.dev/play/p/abcdefgh12", want: godev},
+		{source: "https://go.dev/play/p/abcdefgh12.go", want: godev},
+		{source: "go.dev/play/p/abcdefgh12", want: godev},
+		{source: "go.dev/play/p/abcdefgh12.go", want: godev},
+		{source: "abcdefgh12", want: bare},
+		{source: "abcdefgh12.go", want: bare},
+		{
+			source: "go.dev/play/p/Ab_-efgh12x",
+			want:   snippetSource{id: "Ab_-efgh12x", url: "https://go.dev/play/p/Ab_-efgh12x.go"},
+		},
+		{
+			source: "https://gist.github.com/someone/0123abcd",
+			want:   snippetSource{id: "0123abcd", url: "https://gist.github.com/someone/0123abcd/raw", external: true},
+		},
+		{
+			source: "raw.githubusercontent.com/a/b/main/x.go",
+			want:   snippetSource{id: "x.go", url: "https://raw.githubusercontent.com/a/b/main/x.go", external: true},
+		},
+		{source: "https://go.dev/play/p/short", wantErr: true},
+		{source: "short", wantErr: true},
+		{source: "abcdefgh", wantErr: true},
+		{source: "abcdefgh1234", wantErr: true},
+		{source: "https://example.com/abcdefgh12", wantErr: true},
+		{source: "abcdefgh12!", wantErr: true},
+		{source: "play.golang.org/p/abcdefgh12/extra", wantErr: true},
+		{source: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := parseSnippetSource(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSnippetSource() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseSnippetSource() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapEvalCode(t *testing.T) {
+	tmpl, err := parseEvalTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := func(body string) string { return "package main\n\nfunc main() {\n\t" + body + "\n}\n" }
+	const program = "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }"
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "expression", code: "fmt.Println(1)", want: wrapped("fmt.Println(1)")},
+		{name: "statements", code: "x := 1; x++; fmt.Println(x)", want: wrapped("x := 1; x++; fmt.Println(x)")},
+		{name: "full program", code: program, want: program},
+		{name: "leading whitespace", code: "\n  " + program, want: "\n  " + program},
+		{name: "other package", code: "package foo\nfunc X() {}", want: "package foo\nfunc X() {}"},
+		{name: "main without package", code: "func main() {}", want: "package main\nfunc main() {}"},
+		{name: "package in a string", code: `println("package main")`, want: wrapped(`println("package main")`)},
+		{name: "other func", code: "func() { println(1) }()", want: wrapped("func() { println(1) }()")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wrapEvalCode(tmpl, tt.code, 0, false)
+			if err != nil {
+				t.Fatalf("wrapEvalCode() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("wrapEvalCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	seeded, err := wrapEvalCode(tmpl, "println(rand.Intn(10))", 42, true)
+	if err != nil {
+		t.Fatalf("wrapEvalCode() with a seed error = %v", err)
+	}
+
+	if want := wrapped("rand := rand.New(rand.NewSource(42))\n_ = rand\nprintln(rand.Intn(10))"); seeded != want {
+		t.Errorf("wrapEvalCode() with a seed = %q, want %q", seeded, want)
+	}
+
+	if _, err := wrapEvalCode(tmpl, program, 1, true); err == nil {
+		t.Error("wrapEvalCode() with a seed and a full program succeeded, want error")
+	}
+}
+
+func TestJoinEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*goplay.Event
+		want   string
+	}{
+		{name: "no events", want: ""},
+		{name: "single", events: []*goplay.Event{stdout("hello\n")}, want: "hello\n"},
+		{
+			name:   "interleaved",
+			events: []*goplay.Event{stdout("out 1\n"), stderr("err 1\n"), stdout("out 2\n"), stderr("err 2\n")},
+			want:   "out 1\nerr 1\nout 2\nerr 2\n",
+		},
+		{
+			name:   "partial lines",
+			events: []*goplay.Event{stdout("a"), stderr("b\n"), stdout("c")},
+			want:   "ab\nc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &runResult{Response: goplay.Response{Events: tt.events}}
+			if got := joinEvents(res); got != tt.want {
+				t.Errorf("joinEvents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var errPlaygroundDown = errors.New("the playground appears to be down, try again later")
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // Requests are allowed
+	breakerOpen                         // Requests are refused until the cooldown expires
+	breakerHalfOpen                     // A single probe request is allowed, to check if the playground has recovered
+)
+
+// circuitBreaker stops requests being made to the playground after a number of consecutive failures, for a cooldown
+// period. Once the cooldown has expired, a single request is allowed through to probe whether the playground has
+// recovered.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns whether or not a request should be made. Callers that are allowed must report the outcome with
+// success or failure.
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case breakerOpen:
+		if time.Since(c.openedAt) < c.cooldown {
+			return false
+		}
+
+		c.state = breakerHalfOpen
+		c.probing = true
+		return true
+
+	case breakerHalfOpen:
+		if c.probing {
+			return false
+		}
+
+		c.probing = true
+		return true
+	}
+
+	return true
+}
+
+// success records a successful request, closing the breaker.
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = breakerClosed
+	c.failures = 0
+	c.probing = false
+}
+
+// failure records a failed request, opening the breaker if the threshold has been reached or a probe failed.
+func (c *circuitBreaker) failure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	c.probing = false
+	if c.state == breakerHalfOpen || c.failures >= c.threshold {
+		c.state = breakerOpen
+		c.openedAt = time.Now()
+	}
+}
+
+// String describes the current state of the breaker.
+func (c *circuitBreaker) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case breakerOpen:
+		remaining := c.cooldown - time.Since(c.openedAt)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		return fmt.Sprintf(
+			"down after %d consecutive failures, retrying in %s", c.failures, remaining.Round(time.Second),
+		)
+
+	case breakerHalfOpen:
+		return "recovering, checking if it is back up"
+	}
+
+	if c.failures > 0 {
+		return fmt.Sprintf("up (%d recent failures)", c.failures)
+	}
+
+	return "up"
+}
+
+// StatusCmd is the callback for the ~status IRC command, and reports whether or not the playground is reachable.
+func (b *Bot) StatusCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	reply("Playground: %s", b.breaker)
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+// expire makes an open breaker's cooldown expire, without waiting for it.
+func (c *circuitBreaker) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.openedAt = time.Now().Add(-c.cooldown)
+}
+
+func assertBreaker(t *testing.T, c *circuitBreaker, want breakerState) {
+	t.Helper()
+
+	c.mu.Lock()
+	got := c.state
+	c.mu.Unlock()
+
+	if got != want {
+		t.Fatalf("breaker state = %d, want %d", got, want)
+	}
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	c := newCircuitBreaker(3, time.Minute)
+	for i := 0; i < 2; i++ {
+		if !c.allow() {
+			t.Fatalf("allow() = false after %d failures, want true", i)
+		}
+
+		c.failure()
+		assertBreaker(t, c, breakerClosed)
+	}
+
+	c.allow()
+	c.failure()
+	assertBreaker(t, c, breakerOpen)
+
+	if c.allow() {
+		t.Error("allow() = true while open, want false")
+	}
+}
+
+func TestCircuitBreakerSuccessResets(t *testing.T) {
+	c := newCircuitBreaker(2, time.Minute)
+	c.allow()
+	c.failure()
+	c.allow()
+	c.success()
+
+	// The count of consecutive failures starts again
+	c.allow()
+	c.failure()
+	assertBreaker(t, c, breakerClosed)
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	c := newCircuitBreaker(1, time.Minute)
+	c.allow()
+	c.failure()
+	assertBreaker(t, c, breakerOpen)
+
+	c.expire()
+	if !c.allow() {
+		t.Fatal("allow() = false after the cooldown, want a probe")
+	}
+
+	assertBreaker(t, c, breakerHalfOpen)
+	if c.allow() {
+		t.Error("allow() = true while a probe is in flight, want false")
+	}
+
+	// A failed probe reopens the breaker for another cooldown
+	c.failure()
+	assertBreaker(t, c, breakerOpen)
+	if c.allow() {
+		t.Error("allow() = true after a failed probe, want false")
+	}
+
+	// and a successful one closes it
+	c.expire()
+	c.allow()
+	c.success()
+	assertBreaker(t, c, breakerClosed)
+	if !c.allow() || !c.allow() {
+		t.Error("allow() = false once closed, want true")
+	}
+}
+
+func TestCircuitBreakerString(t *testing.T) {
+	c := newCircuitBreaker(2, time.Minute)
+	if got := c.String(); got != "up" {
+		t.Errorf("String() = %q, want %q", got, "up")
+	}
+
+	c.allow()
+	c.failure()
+	if got, want := c.String(), "up (1 recent failures)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	c.allow()
+	c.failure()
+	if got, want := c.String(), "down after 2 consecutive failures, retrying in 1m0s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	c.expire()
+	c.allow()
+	if got, want := c.String(), "recovering, checking if it is back up"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
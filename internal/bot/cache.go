@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultCache is an LRU cache of playground results, keyed by the sha256 of the code that was run. The playground's
+// clock is fixed, so most code gives the same result every time, but not all: it may depend on the network or on
+// changes to the playground. Results are only kept for the TTL, trading a chance of a stale result for not running
+// the same code again and again. Runs known to vary are never cached, see cacheable.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	res     *runResult
+	share   string
+	expires time.Time
+}
+
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// get returns the cached result for code, if there is an unexpired one, marked as Cached. If needShare is set, results
+// cached without a share link are ignored.
+func (c *resultCache) get(code []byte, needShare bool) (*runResult, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[sha256.Sum256(code)]
+	if !ok {
+		return nil, "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		return nil, "", false
+	}
+
+	if needShare && entry.share == "" {
+		return nil, "", false
+	}
+
+	c.order.MoveToFront(elem)
+	res := *entry.res
+	res.Cached = true
+	return &res, entry.share, true
+}
+
+// put caches the result of running code, evicting the least recently used result if the cache is full.
+func (c *resultCache) put(code []byte, res *runResult, share string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sha256.Sum256(code)
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+	}
+
+	entry := &cacheEntry{key: key, res: res, share: share, expires: time.Now().Add(c.ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// clear removes all cached results, returning how many there were.
+func (c *resultCache) clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.order.Len()
+	c.order.Init()
+	c.entries = make(map[[sha256.Size]byte]*list.Element)
+	return n
+}
+
+// EvalCacheCmd is the callback for the ~evalcache IRC command. Currently the only subcommand is clear.
+func (b *Bot) EvalCacheCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	if strings.TrimSpace(args) != "clear" {
+		reply("Usage: evalcache clear")
+		return
+	}
+
+	reply("Cleared %d cached results", b.cache.clear())
+}
+
+// cacheable returns whether or not the result of running code, with stdin if it is not nil, may be cached. Runs given
+// stdin aren't, nor are ones using randomness that --seed didn't make reproducible: crypto/rand, math/rand/v2, or
+// math/rand, which is randomly seeded since Go 1.20.
+func cacheable(code []byte, stdin *string) bool {
+	if stdin != nil {
+		return false
+	}
+
+	for _, file := range splitTxtar(code) {
+		if !strings.HasSuffix(file.name, ".go") {
+			continue
+		}
+
+		// Code that doesn't parse fails to compile the same way every time
+		f, err := parser.ParseFile(token.NewFileSet(), file.name, file.data, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+
+		for _, spec := range f.Imports {
+			switch path, _ := strconv.Unquote(spec.Path.Value); path {
+			case "crypto/rand", "math/rand/v2":
+				return false
+			case "math/rand":
+				if !strings.Contains(string(file.data), seededRandPrefix) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultCache(t *testing.T) {
+	c := newResultCache(2, time.Minute)
+	res := &runResult{Status: 1, Elapsed: time.Second}
+
+	if _, _, ok := c.get([]byte("a"), false); ok {
+		t.Fatal("get() on an empty cache succeeded")
+	}
+
+	c.put([]byte("a"), res, "")
+	got, share, ok := c.get([]byte("a"), false)
+	if !ok || got.Status != 1 || share != "" {
+		t.Fatalf("get() = %+v, %q, %t; want the cached result", got, share, ok)
+	}
+
+	if !got.Cached {
+		t.Error("get() result is not marked as cached")
+	}
+
+	if res.Cached {
+		t.Error("get() marked the result passed to put() as cached")
+	}
+
+	if _, _, ok := c.get([]byte("a"), true); ok {
+		t.Error("get() needing a share link returned a result cached without one")
+	}
+
+	c.put([]byte("b"), res, "https://go.dev/play/p/abcdefgh12")
+	if _, share, ok := c.get([]byte("b"), true); !ok || share != "https://go.dev/play/p/abcdefgh12" {
+		t.Errorf("get() needing a share link = %q, %t; want the cached link", share, ok)
+	}
+
+	// a was used less recently than b, so is evicted first
+	c.get([]byte("b"), false)
+	c.put([]byte("c"), res, "")
+	if _, _, ok := c.get([]byte("a"), false); ok {
+		t.Error("get() returned the least recently used result after it should have been evicted")
+	}
+
+	for _, key := range []string{"b", "c"} {
+		if _, _, ok := c.get([]byte(key), false); !ok {
+			t.Errorf("get(%q) failed, want it to still be cached", key)
+		}
+	}
+
+	if n := c.clear(); n != 2 {
+		t.Errorf("clear() = %d, want 2", n)
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	c := newResultCache(2, -time.Second)
+	c.put([]byte("a"), &runResult{}, "")
+	if _, _, ok := c.get([]byte("a"), false); ok {
+		t.Error("get() returned an expired result")
+	}
+
+	if c.order.Len() != 0 {
+		t.Errorf("expired result was not removed, %d entries remain", c.order.Len())
+	}
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	c := newResultCache(-1, time.Minute)
+	c.put([]byte("a"), &runResult{}, "")
+	if _, _, ok := c.get([]byte("a"), false); ok {
+		t.Error("get() on a disabled cache succeeded")
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	const plain = "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n"
+	const unseeded = "package main\n\nimport \"math/rand\"\n\nfunc main() { println(rand.Int()) }\n"
+	seeded := "package main\n\nimport \"math/rand\"\n\nfunc main() {\n" + fmt.Sprintf(seededRandCode, 1) +
+		"println(rand.Int())\n}\n"
+	stdin := "input"
+
+	tests := []struct {
+		name  string
+		code  string
+		stdin *string
+		want  bool
+	}{
+		{name: "plain", code: plain, want: true},
+		{name: "stdin", code: plain, stdin: &stdin},
+		{name: "unseeded math/rand", code: unseeded},
+		{name: "seeded math/rand", code: seeded, want: true},
+		{name: "crypto/rand", code: strings.Replace(unseeded, "math/rand", "crypto/rand", 1)},
+		{name: "math/rand/v2", code: strings.Replace(unseeded, "math/rand", "math/rand/v2", 1)},
+		{name: "unseeded in a second file", code: "-- prog.go --\n" + plain + "-- other.go --\n" + unseeded},
+		{name: "does not parse", code: "package main\n\nimport (", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cacheable([]byte(tt.code), tt.stdin); got != tt.want {
+				t.Errorf("cacheable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRunTime(t *testing.T) {
+	res := &runResult{Elapsed: 1234 * time.Millisecond}
+	if got := formatRunTime(res); got != "1.2s" {
+		t.Errorf("formatRunTime() = %q, want %q", got, "1.2s")
+	}
+
+	res.Cached = true
+	if got := formatRunTime(res); got != "cached" {
+		t.Errorf("formatRunTime() for a cached result = %q, want %q", got, "cached")
+	}
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+// requestedCaps are the IRCv3 capabilities requested from the server. account-tag identifies admins and users by
+// account, server-time lets replayed messages be recognised, and message-tags is needed for tags to be sent at all on
+// some servers.
+var requestedCaps = []string{"account-tag", "server-time", "message-tags"}
+
+// updateCaps records the capabilities the server acknowledged, on connecting.
+func (b *Bot) updateCaps() {
+	caps := b.irc.AcknowledgedCaps()
+
+	b.capsMu.Lock()
+	defer b.capsMu.Unlock()
+	b.caps = caps
+}
+
+// hasCap returns whether or not the server acknowledged the named capability.
+func (b *Bot) hasCap(name string) bool {
+	b.capsMu.Lock()
+	defer b.capsMu.Unlock()
+	_, ok := b.caps[name]
+	return ok
+}
+
+// serverTime returns the time msg was sent according to its server-time tag, or the zero time if it has none.
+func serverTime(msg ircmsg.Message) time.Time {
+	present, value := msg.GetTag("time")
+	if !present {
+		return time.Time{}
+	}
+
+	sent, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return sent
+}
+
+// isReplayed returns whether or not msg was sent before the bot connected, according to its server-time tag. Both
+// times come from the server's clock, so it doesn't matter if that is wrong. If the server didn't tag the end of
+// registration, there is nothing to compare against, and nothing is treated as replayed.
+func (b *Bot) isReplayed(msg ircmsg.Message) bool {
+	sent := serverTime(msg)
+	if sent.IsZero() {
+		return false
+	}
+
+	b.connectedMu.Lock()
+	registeredAt := b.registeredAt
+	b.connectedMu.Unlock()
+
+	return !registeredAt.IsZero() && sent.Before(registeredAt)
+}
+
+// logReplayed logs that commands in msg were ignored as it was replayed. The first is logged as a warning, so that a
+// problem with server-time doesn't silently stop the bot responding, and the rest since connecting are only debug.
+func (b *Bot) logReplayed(msg ircmsg.Message) {
+	b.connectedMu.Lock()
+	warned := b.replayWarned
+	b.replayWarned = true
+	b.connectedMu.Unlock()
+
+	if warned {
+		debugf("Ignoring replayed message from %s: %q", msg.Prefix, msg.Params[1])
+		return
+	}
+
+	warnf("Ignoring commands in messages sent before connecting, according to server-time, starting with %q from %s",
+		msg.Params[1], msg.Prefix)
+}
+
+// CapsCmd is the callback for the ~caps IRC command, and lists the capabilities the server acknowledged.
+func (b *Bot) CapsCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	b.capsMu.Lock()
+	caps := make([]string, 0, len(b.caps))
+	for name, value := range b.caps {
+		if value != "" {
+			name += "=" + value
+		}
+
+		caps = append(caps, name)
+	}
+	b.capsMu.Unlock()
+
+	if len(caps) == 0 {
+		reply("No capabilities were negotiated")
+		return
+	}
+
+	sort.Strings(caps)
+	reply("Capabilities: %s", strings.Join(caps, ", "))
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+// taggedPrivmsg builds a PRIVMSG with a server-time tag of sent, or none if sent is zero.
+func taggedPrivmsg(sent time.Time) ircmsg.Message {
+	msg := privmsgFrom("user!u@host", "#go", "~echo hi")
+	if !sent.IsZero() {
+		msg.SetTag("time", sent.UTC().Format(time.RFC3339Nano))
+	}
+
+	return msg
+}
+
+func TestIsReplayed(t *testing.T) {
+	now := time.Now()
+	lagging := now.Add(-2 * time.Hour) // a server whose clock is two hours behind ours
+
+	tests := []struct {
+		name       string
+		registered time.Time
+		sent       time.Time
+		want       bool
+	}{
+		{name: "untagged", registered: now},
+		{name: "after registration", registered: now, sent: now.Add(time.Second)},
+		{name: "before registration", registered: now, sent: now.Add(-time.Second), want: true},
+		{name: "lagging clock, live", registered: lagging, sent: lagging.Add(time.Second)},
+		{name: "lagging clock, replayed", registered: lagging, sent: lagging.Add(-time.Hour), want: true},
+		{name: "untagged registration", sent: now.Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBot(t, nil)
+			b.markConnected(taggedPrivmsg(tt.registered))
+
+			if got := b.isReplayed(taggedPrivmsg(tt.sent)); got != tt.want {
+				t.Errorf("isReplayed() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnPrivmsgReplayed(t *testing.T) {
+	b, f := newDispatchBot(t, nil)
+	registered := time.Now().Add(-2 * time.Hour)
+	b.markConnected(taggedPrivmsg(registered))
+
+	b.onPrivmsg(taggedPrivmsg(registered.Add(-time.Minute)))
+	assertPrivmsgs(t, f)
+
+	b.onPrivmsg(taggedPrivmsg(registered.Add(time.Minute)))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: hi"})
+}
@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+func (b *Bot) isSelf(prefix string) bool {
+	nick, _, _ := ircevent.SplitNUH(prefix)
+	return strings.EqualFold(nick, b.conn.CurrentNick())
+}
+
+func (b *Bot) onJoin(msg ircmsg.Message) {
+	if len(msg.Params) < 1 || !b.isSelf(msg.Prefix) {
+		return
+	}
+
+	b.channelsMu.Lock()
+	b.channels[strings.ToLower(msg.Params[0])] = time.Now()
+	b.channelsMu.Unlock()
+}
+
+func (b *Bot) onPart(msg ircmsg.Message) {
+	if len(msg.Params) < 1 || !b.isSelf(msg.Prefix) {
+		return
+	}
+
+	b.removeChannel(msg.Params[0])
+}
+
+func (b *Bot) onKick(msg ircmsg.Message) {
+	if len(msg.Params) < 2 || !strings.EqualFold(msg.Params[1], b.conn.CurrentNick()) {
+		return
+	}
+
+	b.removeChannel(msg.Params[0])
+}
+
+func (b *Bot) removeChannel(channel string) {
+	b.channelsMu.Lock()
+	delete(b.channels, strings.ToLower(channel))
+	b.channelsMu.Unlock()
+}
+
+// resetChannels forgets all joined channels, it should be called whenever a new connection is made.
+func (b *Bot) resetChannels() {
+	b.channelsMu.Lock()
+	b.channels = make(map[string]time.Time)
+	b.channelsMu.Unlock()
+}
+
+// markChannelActive records that a command was run in the given channel. Targets the bot has not joined (such as
+// private messages) are ignored.
+func (b *Bot) markChannelActive(channel string) {
+	b.channelsMu.Lock()
+	defer b.channelsMu.Unlock()
+
+	channel = strings.ToLower(channel)
+	if _, ok := b.channels[channel]; ok {
+		b.channels[channel] = time.Now()
+	}
+}
+
+// splitChannelKey splits an entry in JoinChannels, either "#channel" or "#channel key", into the channel and its key.
+func splitChannelKey(entry string) (channel, key string) {
+	fields := strings.Fields(entry)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	default:
+		return fields[0], fields[1]
+	}
+}
+
+// channelNames returns the channels in entries, without their keys.
+func channelNames(entries []string) []string {
+	out := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		channel, _ := splitChannelKey(entry)
+		out = append(out, channel)
+	}
+
+	return out
+}
+
+// validateJoinChannels returns an error if any entry in JoinChannels is not a channel, optionally followed by a key.
+func validateJoinChannels(entries []string) error {
+	for _, entry := range entries {
+		if n := len(strings.Fields(entry)); n == 0 || n > 2 {
+			return fmt.Errorf("invalid join_channels entry %q, expected \"#channel\" or \"#channel key\"", entry)
+		}
+	}
+
+	return nil
+}
+
+// join joins channel, using key if it is not empty.
+func (b *Bot) join(channel, key string) error {
+	if key != "" {
+		return b.conn.Send("JOIN", channel, key)
+	}
+
+	return b.conn.Join(channel)
+}
+
+// isStaticChannel returns whether or not channel is in configured, the lowercased names of the channels in
+// JoinChannels, or was pinned by an admin with "~join --pin". channelsMu must be held.
+func (b *Bot) isStaticChannel(channel string, configured map[string]bool) bool {
+	channel = strings.ToLower(channel)
+	return configured[channel] || b.pinnedChannels[channel]
+}
+
+// inChannel returns whether or not the bot is currently in channel.
+func (b *Bot) inChannel(channel string) bool {
+	b.channelsMu.Lock()
+	defer b.channelsMu.Unlock()
+	_, ok := b.channels[strings.ToLower(channel)]
+	return ok
+}
+
+// joinChannels joins all configured channels, and any joined at runtime, with their keys.
+func (b *Bot) joinChannels() {
+	b.channelsMu.Lock()
+	channels := append([]string{}, b.cfg().JoinChannels...)
+	for _, ch := range b.extraChannels {
+		channels = append(channels, ch)
+	}
+	b.channelsMu.Unlock()
+
+	for _, entry := range channels {
+		if err := b.join(splitChannelKey(entry)); err != nil {
+			errorf("Unable to join %s: %s", entry, err)
+		}
+	}
+}
+
+// isChannelName returns whether or not name is a channel, according to the server's CHANTYPES.
+func (b *Bot) isChannelName(name string) bool {
+	chanTypes, ok := b.conn.ISupport()["CHANTYPES"]
+	if !ok {
+		chanTypes = "#&"
+	}
+
+	return name != "" && strings.ContainsRune(chanTypes, rune(name[0]))
+}
+
+// part leaves channel with the given reason.
+func (b *Bot) part(channel, reason string) error {
+	return b.conn.Send("PART", channel, reason)
+}
+
+// joinRuntime joins channel, remembering it and its key so that it is rejoined on reconnect. key may be empty. If pin
+// is set, the channel is exempt from auto_part_after.
+func (b *Bot) joinRuntime(channel, key string, pin bool) error {
+	b.channelsMu.Lock()
+	b.extraChannels[strings.ToLower(channel)] = strings.TrimSpace(channel + " " + key)
+	if pin {
+		b.pinnedChannels[strings.ToLower(channel)] = true
+	}
+	b.channelsMu.Unlock()
+
+	return b.join(channel, key)
+}
+
+// onInvite joins channels the bot is invited to by admins. Invites from anyone else are logged and ignored.
+func (b *Bot) onInvite(msg ircmsg.Message) {
+	if len(msg.Params) < 2 || !b.isChannelName(msg.Params[1]) {
+		return
+	}
+
+	channel := msg.Params[1]
+	if !b.isAdmin(msg) {
+		warnf("Ignoring invite to %s from non-admin %s", channel, msg.Prefix)
+		return
+	}
+
+	if b.inChannel(channel) {
+		return
+	}
+
+	infof("Joining %s at the invitation of %s", channel, msg.Prefix)
+	if err := b.joinRuntime(channel, "", false); err != nil {
+		errorf("Unable to join %s: %s", channel, err)
+	}
+}
+
+// JoinCmd is the callback for the ~join IRC command. The joined channel is remembered, and rejoined on reconnect.
+// Channels joined with --pin are never parted for being idle.
+func (b *Bot) JoinCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	split := strings.Fields(args)
+	pin := len(split) > 0 && split[0] == "--pin"
+	if pin {
+		split = split[1:]
+	}
+
+	if len(split) == 0 || len(split) > 2 || !b.isChannelName(split[0]) {
+		reply("Usage: join [--pin] <#channel> [key]")
+		return
+	}
+
+	channel := split[0]
+	if b.inChannel(channel) {
+		reply("Already in %s", channel)
+		return
+	}
+
+	key := ""
+	if len(split) == 2 {
+		key = split[1]
+	}
+
+	if err := b.joinRuntime(channel, key, pin); err != nil {
+		reply("Unable to join %s: %s", channel, err)
+		return
+	}
+
+	reply("Joining %s", channel)
+}
+
+// PartCmd is the callback for the ~part IRC command. The channel will no longer be joined on reconnect unless it is
+// in the config.
+func (b *Bot) PartCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	split := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	if !b.isChannelName(split[0]) {
+		reply("Usage: part <#channel> [reason]")
+		return
+	}
+
+	channel := split[0]
+	reason := "Requested by " + ctx.Nick
+	if len(split) == 2 {
+		reason = split[1]
+	}
+
+	b.forgetRuntimeChannel(channel)
+	if !b.inChannel(channel) {
+		reply("Not in %s", channel)
+		return
+	}
+
+	if err := b.part(channel, reason); err != nil {
+		reply("Unable to part %s: %s", channel, err)
+		return
+	}
+
+	reply("Parting %s", channel)
+}
+
+// autoPartLoop periodically parts any channels that have not had a command run in them for AutoPartAfter. It never
+// returns.
+func (b *Bot) autoPartLoop() {
+	interval := b.cfg().AutoPartAfter / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+
+	for range time.Tick(interval) {
+		b.partIdleChannels()
+	}
+}
+
+// forgetRuntimeChannel stops channel from being rejoined on reconnect, and unpins it.
+func (b *Bot) forgetRuntimeChannel(channel string) {
+	b.channelsMu.Lock()
+	delete(b.extraChannels, strings.ToLower(channel))
+	delete(b.pinnedChannels, strings.ToLower(channel))
+	b.channelsMu.Unlock()
+}
+
+// partIdleChannels parts every channel that has not had a command run in it for AutoPartAfter, other than those in
+// JoinChannels or pinned. Channels joined at runtime are forgotten, so they are not rejoined on reconnect.
+func (b *Bot) partIdleChannels() {
+	c := b.cfg()
+	configured := make(map[string]bool, len(c.JoinChannels))
+	for _, ch := range channelNames(c.JoinChannels) {
+		configured[strings.ToLower(ch)] = true
+	}
+
+	var idle []string
+	b.channelsMu.Lock()
+	for ch, lastActive := range b.channels {
+		if time.Since(lastActive) > c.AutoPartAfter && !b.isStaticChannel(ch, configured) {
+			idle = append(idle, ch)
+		}
+	}
+	b.channelsMu.Unlock()
+
+	for _, ch := range idle {
+		b.forgetRuntimeChannel(ch)
+		infof("Parting %s: no commands run for %s", ch, c.AutoPartAfter)
+		if err := b.part(ch, "Idle"); err != nil {
+			errorf("Unable to part %s: %s", ch, err)
+		}
+	}
+}
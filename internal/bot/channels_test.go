@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPartIdleChannels(t *testing.T) {
+	b := newTestBot(t, func(c *BotConfig) {
+		c.JoinChannels = []string{"#static key"}
+		c.AutoPartAfter = time.Hour
+	})
+	f := useFakeConn(b)
+
+	if err := b.joinRuntime("#invited", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.joinRuntime("#Pinned", "", true); err != nil {
+		t.Fatal(err)
+	}
+
+	idle := time.Now().Add(-2 * time.Hour)
+	b.channels = map[string]time.Time{"#static": idle, "#invited": idle, "#pinned": idle, "#active": time.Now()}
+	f.raw = nil
+
+	b.partIdleChannels()
+
+	if want := []string{"PART #invited Idle"}; !reflect.DeepEqual(f.raw, want) {
+		t.Errorf("partIdleChannels() sent %q, want %q", f.raw, want)
+	}
+
+	var remembered []string
+	for ch := range b.extraChannels {
+		remembered = append(remembered, ch)
+	}
+
+	sort.Strings(remembered)
+	if want := []string{"#pinned"}; !reflect.DeepEqual(remembered, want) {
+		t.Errorf("channels rejoined on reconnect = %q, want %q", remembered, want)
+	}
+}
+
+func TestJoinCmdPin(t *testing.T) {
+	tests := []struct {
+		args       string
+		wantRaw    string
+		wantPinned bool
+		wantReply  string
+	}{
+		{args: "#go", wantRaw: "JOIN #go", wantReply: "Joining #go"},
+		{args: "--pin #go key", wantRaw: "JOIN #go key", wantPinned: true, wantReply: "Joining #go"},
+		{args: "--pin", wantReply: "Usage: join [--pin] <#channel> [key]"},
+		{args: "#go --pin extra", wantReply: "Usage: join [--pin] <#channel> [key]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			b := newTestBot(t, nil)
+			f := useFakeConn(b)
+
+			b.JoinCmd(nil, tt.args, b.newReplyFunc("admin", "admin", false))
+			assertPrivmsgs(t, f, fakePrivmsg{"admin", tt.wantReply})
+
+			var wantRaw []string
+			if tt.wantRaw != "" {
+				wantRaw = []string{tt.wantRaw}
+			}
+
+			if !reflect.DeepEqual(f.raw, wantRaw) {
+				t.Errorf("JoinCmd() sent %q, want %q", f.raw, wantRaw)
+			}
+
+			if got := b.pinnedChannels["#go"]; got != tt.wantPinned {
+				t.Errorf("JoinCmd() pinned = %t, want %t", got, tt.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// checkCode rejects code that uses an import in disallowed, or, if rejectEmptyLoops is set, contains an empty infinite
+// loop, which could only ever time out on the playground. Every .go file in code is checked if it is a txtar archive.
+func checkCode(code []byte, disallowed []string, rejectEmptyLoops bool) error {
+	if len(disallowed) == 0 && !rejectEmptyLoops {
+		return nil
+	}
+
+	for _, file := range splitTxtar(code) {
+		if !strings.HasSuffix(file.name, ".go") {
+			continue
+		}
+
+		if err := checkFile(file.name, file.data, disallowed, rejectEmptyLoops); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkFile applies checkCode's checks to a single Go file. If disallowed is not empty, a file whose imports can't be
+// parsed is rejected, as it can't be checked. Empty loops in a file that doesn't parse are let through, so that the
+// playground can report the errors.
+func checkFile(name string, src []byte, disallowed []string, rejectEmptyLoops bool) error {
+	fset := token.NewFileSet()
+	if len(disallowed) > 0 {
+		f, err := parser.ParseFile(fset, name, src, parser.ImportsOnly)
+		if err != nil {
+			return fmt.Errorf("unable to check imports: %w", err)
+		}
+
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			for _, pattern := range disallowed {
+				if importMatches(pattern, path) {
+					return fmt.Errorf("import %q is not allowed", path)
+				}
+			}
+		}
+	}
+
+	if !rejectEmptyLoops {
+		return nil
+	}
+
+	f, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		return nil
+	}
+
+	var found bool
+	ast.Inspect(f, func(n ast.Node) bool {
+		if loop, ok := n.(*ast.ForStmt); ok && loop.Init == nil && loop.Cond == nil && loop.Post == nil &&
+			len(loop.Body.List) == 0 {
+			found = true
+		}
+
+		return !found
+	})
+
+	if found {
+		return errors.New("code contains an empty infinite loop (for {}), which would only time out")
+	}
+
+	return nil
+}
+
+// importMatches returns whether path matches pattern, which is either an import path, or one ending in /... to match
+// it and everything below it, as with the go tool.
+func importMatches(pattern, path string) bool {
+	if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+
+	return path == pattern
+}
+
+// txtarFile is a single file from a txtar archive, see splitTxtar.
+type txtarFile struct {
+	name string
+	data []byte
+}
+
+// splitTxtar splits code in the txtar format the playground accepts into its files. As on the playground, anything
+// before the first file header is prog.go, and code with no headers is a single prog.go.
+func splitTxtar(code []byte) []txtarFile {
+	var files []txtarFile
+	var leading []byte
+	for _, line := range bytes.SplitAfter(code, []byte("\n")) {
+		if name, ok := txtarHeader(line); ok {
+			files = append(files, txtarFile{name: name})
+			continue
+		}
+
+		if len(files) == 0 {
+			leading = append(leading, line...)
+		} else {
+			files[len(files)-1].data = append(files[len(files)-1].data, line...)
+		}
+	}
+
+	if len(files) == 0 || len(bytes.TrimSpace(leading)) > 0 {
+		files = append([]txtarFile{{name: "prog.go", data: leading}}, files...)
+	}
+
+	return files
+}
+
+// txtarHeader returns the file name from line, if it is a txtar file header: "-- name --".
+func txtarHeader(line []byte) (string, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("-- ")) || !bytes.HasSuffix(line, []byte(" --")) || len(line) < len("-- x --") {
+		return "", false
+	}
+
+	name := strings.TrimSpace(string(line[len("-- ") : len(line)-len(" --")]))
+	return name, name != ""
+}
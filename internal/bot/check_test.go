@@ -0,0 +1,94 @@
+package bot
+
+import "testing"
+
+func TestCheckCode(t *testing.T) {
+	const netProg = "package main\n\nimport \"net\"\n\nfunc main() { net.Dial(\"tcp\", \"x\") }\n"
+	const loopProg = "package main\n\nfunc main() {\n\tfor {\n\t}\n}\n"
+	const okProg = "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n"
+
+	tests := []struct {
+		name       string
+		code       string
+		disallowed []string
+		loops      bool
+		wantErr    bool
+	}{
+		{name: "no restrictions", code: netProg},
+		{name: "allowed", code: okProg, disallowed: []string{"net"}, loops: true},
+		{name: "disallowed import", code: netProg, disallowed: []string{"net"}, wantErr: true},
+		{name: "disallowed subtree", code: netProg, disallowed: []string{"net/..."}, wantErr: true},
+		{name: "other subtree", code: netProg, disallowed: []string{"net/http/..."}},
+		{name: "empty loop", code: loopProg, loops: true, wantErr: true},
+		{name: "empty loop allowed", code: loopProg, disallowed: []string{"net"}},
+		{
+			name: "with stdin", code: withStdin(netProg, "input"), disallowed: []string{"net"},
+			wantErr: true,
+		},
+		{
+			name: "second txtar file", code: "-- prog.go --\n" + okProg + "-- other.go --\n" + netProg,
+			disallowed: []string{"net"}, wantErr: true,
+		},
+		{
+			name: "txtar with leading prog.go", code: netProg + "-- other.go --\n" + okProg,
+			disallowed: []string{"net"}, wantErr: true,
+		},
+		{
+			name: "txtar loop", code: "-- prog.go --\n" + okProg + "-- loop.go --\n" + loopProg,
+			loops: true, wantErr: true,
+		},
+		{
+			name: "non-go txtar files are skipped", code: "-- prog.go --\n" + okProg + "-- go.mod --\nmodule x\n",
+			disallowed: []string{"net"},
+		},
+		{
+			name: "unparseable imports with restrictions", code: "package main\nimport net\"\n",
+			disallowed: []string{"net"}, wantErr: true,
+		},
+		{name: "unparseable body with loop check", code: "package main\nfunc main() { for {\n", loops: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCode([]byte(tt.code), tt.disallowed, tt.loops)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCode() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitTxtar(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{name: "plain code", code: "package main\n", want: []string{"prog.go"}},
+		{name: "headers", code: "-- prog.go --\npackage x\n-- a.go --\npackage x\n", want: []string{"prog.go", "a.go"}},
+		{name: "leading code", code: "package main\n-- a.go --\npackage main\n", want: []string{"prog.go", "a.go"}},
+		{name: "blank leading code", code: "\n\n-- a.go --\npackage main\n", want: []string{"a.go"}},
+		{name: "not a header", code: "-- a.go\n--  --\n", want: []string{"prog.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := splitTxtar([]byte(tt.code))
+			var got []string
+			for _, f := range files {
+				got = append(got, f.name)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitTxtar() files = %q, want %q", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("splitTxtar() files = %q, want %q", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/haya14busa/goplay"
+)
+
+// runResult is the playground's response to running a program.
+type runResult struct {
+	goplay.Response
+	Status int // The program's exit status
+
+	// Elapsed is how long the playground took to respond, reported by the --time flag and show_run_time
+	Elapsed time.Duration `json:"-"`
+	Cached  bool          `json:"-"` // set on results returned from the cache, whose Elapsed is from an earlier run
+}
+
+// formatElapsed formats how long a run took for replies, to the millisecond under a second, and tenths of a second
+// above.
+func formatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// formatRunTime describes how long res took for --time and show_run_time. Cached results took no time at all, so
+// they are marked as cached instead of repeating the original run's time.
+func formatRunTime(res *runResult) string {
+	if res.Cached {
+		return "cached"
+	}
+
+	return formatElapsed(res.Elapsed)
+}
+
+// playgroundBackends are the backends the playground can run code on: the current release by default, gotip for the
+// development tree, and goprev for the previous release.
+var playgroundBackends = []string{"", "gotip", "goprev"}
+
+// validateBackend returns an error if backend is not one of playgroundBackends.
+func validateBackend(backend string) error {
+	for _, known := range playgroundBackends {
+		if backend == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown playground backend %q, expected gotip or goprev", backend)
+}
+
+// backendFor returns the backend to run code on, from the --backend flag in flags or the configured default.
+func (b *Bot) backendFor(flags map[string]string) (string, error) {
+	backend, ok := flags["backend"]
+	if !ok {
+		return b.cfg().PlaygroundBackend, nil
+	}
+
+	return backend, validateBackend(backend)
+}
+
+// compile sends code to the playground to be compiled and run on backend. goplay's Response has no exit status, and
+// goplay has no way to choose a backend, so the /compile endpoint is called directly.
+func (b *Bot) compile(ctx context.Context, code []byte, backend string) (*runResult, error) {
+	endpoint := defaultPlayground + "/compile"
+	if backend != "" {
+		endpoint += "?" + url.Values{"backend": {backend}}.Encode()
+	}
+
+	body := strings.NewReader(url.Values{"version": {"2"}, "body": {string(code)}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from playground: %s", res.Status)
+	}
+
+	var out runResult
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("unable to decode compile response: %w", err)
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"strings"
+)
+
+// compileOnlyFlags are the flags that can precede the code passed to ~compileonly.
+var compileOnlyFlags = []flagSpec{{name: "no-imports"}, {name: "backend", hasValue: true}}
+
+// CompileOnlyCmd is the callback for the ~compileonly IRC command. It wraps code as ~eval does, and responds with
+// whether or not it compiles. The playground has no way to compile without running, so the program is still run, but
+// its output is never shown, and nothing is shared.
+func (b *Bot) CompileOnlyCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	flags, args, err := parseFlags(args, compileOnlyFlags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	_, noImports := flags["no-imports"]
+	backend, err := b.backendFor(flags)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	if strings.TrimSpace(args) == "" {
+		reply("Cannot compile empty code")
+		return
+	}
+
+	if err := b.checkSourceSize(args); err != nil {
+		reply("%s", err)
+		return
+	}
+
+	wrapped, err := wrapEvalCode(b.evalTemplate, args, 0, false)
+	if err != nil {
+		reply("%s", err)
+		return
+	}
+
+	res, _, err := b.runCode(context.Background(), wrapped, nil, backend, false, !noImports, true)
+	if err != nil {
+		errorf("Unable to start compile: %s", err)
+		ctx.Fail(err)
+		reply("Unable to start compile: %s", explainOrTrim(err.Error()))
+		return
+	}
+
+	if len(res.Errors) != 0 {
+		debugf("Error while running compile: %s", res.Errors)
+		reply("Errors: %s", b.formatCompileErrors(res.Errors))
+		return
+	}
+
+	reply("Compiled successfully")
+}
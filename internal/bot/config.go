@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// ExtraAdminsEnv is the environment variable from which additional admin masks are read, as a comma separated list.
+// It is intended for deployments where editing the config file is awkward, such as containers.
+const ExtraAdminsEnv = "BOT_EXTRA_ADMINS"
+
+// LoadConfig loads a BotConfig from the toml file at path, and applies any overrides from the environment.
+func LoadConfig(path string) (*BotConfig, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &BotConfig{}
+	if err := tree.Unmarshal(c); err != nil {
+		return nil, fmt.Errorf("could not parse config: %w", err)
+	}
+
+	if extra := os.Getenv(ExtraAdminsEnv); extra != "" {
+		var masks []string
+		for _, m := range strings.Split(extra, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				masks = append(masks, m)
+			}
+		}
+
+		c.Admins = mergeMasks(c.Admins, masks)
+		for i := range c.Networks {
+			if c.Networks[i].Admins != nil {
+				c.Networks[i].Admins = mergeMasks(c.Networks[i].Admins, masks)
+			}
+		}
+	}
+
+	for _, m := range c.Admins {
+		if err := validateMask(m); err != nil {
+			return nil, fmt.Errorf("invalid admin: %w", err)
+		}
+	}
+
+	for _, n := range c.Networks {
+		for _, m := range n.Admins {
+			if err := validateMask(m); err != nil {
+				return nil, fmt.Errorf("network %q: invalid admin: %w", n.Name, err)
+			}
+		}
+	}
+
+	c.path = path
+	return c, nil
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes contents to a config file in a temporary directory, and returns its path.
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// setEnv sets the environment variable key to value for the duration of the test.
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+
+	old, had := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestMergeMasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		masks []string
+		extra []string
+		want  []string
+	}{
+		{name: "nothing extra", masks: []string{"a!b@c"}, want: []string{"a!b@c"}},
+		{name: "nothing configured", extra: []string{"a!b@c"}, want: []string{"a!b@c"}},
+		{name: "appended", masks: []string{"a!b@c"}, extra: []string{"d!e@f"}, want: []string{"a!b@c", "d!e@f"}},
+		{name: "duplicate of config", masks: []string{"a!b@c"}, extra: []string{"A!B@C"}, want: []string{"a!b@c"}},
+		{name: "duplicate within extra", extra: []string{"d!e@f", "d!e@F"}, want: []string{"d!e@f"}},
+		{name: "duplicate within config", masks: []string{"a!b@c", "a!b@c"}, want: []string{"a!b@c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeMasks(tt.masks, tt.extra); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeMasks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExtraAdmins(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "unset", want: []string{"me!*@my/cloak"}},
+		{name: "merged", env: "ops!*@ops/cloak", want: []string{"me!*@my/cloak", "ops!*@ops/cloak"}},
+		{
+			name: "spaces and empty entries", env: " ops!*@ops/cloak, ,other!*@*,",
+			want: []string{"me!*@my/cloak", "ops!*@ops/cloak", "other!*@*"},
+		},
+		{name: "deduped", env: "ME!*@my/cloak,ops!*@*,ops!*@*", want: []string{"me!*@my/cloak", "ops!*@*"}},
+		{name: "invalid", env: "notamask", wantErr: true},
+	}
+
+	path := writeConfig(t, `admins = ["me!*@my/cloak"]`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, ExtraAdminsEnv, tt.env)
+
+			c, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(c.Admins, tt.want) {
+				t.Errorf("LoadConfig() admins = %q, want %q", c.Admins, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigNetworkAdmins(t *testing.T) {
+	setEnv(t, ExtraAdminsEnv, "ops!*@ops/cloak")
+
+	c, err := LoadConfig(writeConfig(t, `
+admins = ["me!*@my/cloak"]
+
+[[networks]]
+name = "libera"
+admins = ["me!*@libera/cloak", "OPS!*@ops/cloak"]
+
+[[networks]]
+name = "oftc"
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		network string
+		want    []string
+	}{
+		{network: "libera", want: []string{"me!*@libera/cloak", "OPS!*@ops/cloak"}},
+		{network: "oftc", want: []string{"me!*@my/cloak", "ops!*@ops/cloak"}},
+	}
+
+	for i, tt := range tests {
+		if got := c.forNetwork(c.Networks[i]).Admins; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s admins = %q, want %q", tt.network, got, tt.want)
+		}
+	}
+
+	setEnv(t, ExtraAdminsEnv, "")
+	if _, err := LoadConfig(writeConfig(t, "[[networks]]\nname = \"libera\"\nadmins = [\"notamask\"]\n")); err == nil {
+		t.Error("LoadConfig() with an invalid network admin succeeded, want error")
+	}
+}
+
+func TestVersionResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "set", config: `version_response = "custom bot 1.0"`, want: "custom bot 1.0"},
+		{name: "default", config: "", want: "goplay-irc " + Version},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			c.Server = "irc.example.com:6697"
+			c.StateFile = filepath.Join(t.TempDir(), "state.toml")
+
+			b, err := New(c)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if b.irc.Version != tt.want {
+				t.Errorf("connection Version = %q, want %q", b.irc.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		wantEval    bool
+		wantPlayrun bool
+	}{
+		{name: "unset", wantEval: true},
+		{name: "set", config: "share_on_eval = false\nshare_on_playrun = true", wantPlayrun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			c.setDefaults()
+			if *c.ShareOnEval != tt.wantEval || *c.ShareOnPlayrun != tt.wantPlayrun {
+				t.Errorf(
+					"share_on_eval, share_on_playrun = %t, %t; want %t, %t",
+					*c.ShareOnEval, *c.ShareOnPlayrun, tt.wantEval, tt.wantPlayrun,
+				)
+			}
+		})
+	}
+
+	// Configs built in code, rather than loaded, get the same defaults
+	c := newTestBot(t, nil).cfg()
+	if !*c.ShareOnEval || *c.ShareOnPlayrun {
+		t.Errorf("share_on_eval, share_on_playrun = %t, %t; want true, false", *c.ShareOnEval, *c.ShareOnPlayrun)
+	}
+}
@@ -0,0 +1,20 @@
+package bot
+
+import "github.com/ergochat/irc-go/ircevent"
+
+// ircConn is the part of the IRC connection used when handling commands. It is satisfied by *ircevent.Connection,
+// and by localConn, so that command handling can be exercised without a server.
+type ircConn interface {
+	CurrentNick() string
+	ISupport() map[string]string
+	Connected() bool
+	Privmsg(target, message string) error
+	Send(command string, params ...string) error
+	SendRaw(message string) error
+	Join(channel string) error
+}
+
+var (
+	_ ircConn = (*ircevent.Connection)(nil)
+	_ ircConn = (*localConn)(nil)
+)
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+// fakeConn is an ircConn that records everything the bot sends, for inspection by tests.
+type fakeConn struct {
+	nick     string
+	isupport map[string]string
+
+	mu       sync.Mutex
+	privmsgs []fakePrivmsg
+	raw      []string
+}
+
+type fakePrivmsg struct {
+	target, text string
+}
+
+var _ ircConn = (*fakeConn)(nil)
+
+// useFakeConn replaces b's connection with a new fakeConn, and returns it.
+func useFakeConn(b *Bot) *fakeConn {
+	f := &fakeConn{nick: b.cfg().Nick}
+	b.conn = f
+	return f
+}
+
+func (f *fakeConn) CurrentNick() string         { return f.nick }
+func (f *fakeConn) ISupport() map[string]string { return f.isupport }
+func (f *fakeConn) Connected() bool             { return true }
+func (f *fakeConn) Join(channel string) error   { return f.Send("JOIN", channel) }
+
+func (f *fakeConn) Privmsg(target, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.privmsgs = append(f.privmsgs, fakePrivmsg{target: target, text: text})
+	return nil
+}
+
+func (f *fakeConn) Send(command string, params ...string) error {
+	return f.SendRaw(strings.TrimSpace(command + " " + strings.Join(params, " ")))
+}
+
+func (f *fakeConn) SendRaw(message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.raw = append(f.raw, message)
+	return nil
+}
+
+// takePrivmsgs returns the PRIVMSGs sent since it was last called.
+func (f *fakeConn) takePrivmsgs() []fakePrivmsg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := f.privmsgs
+	f.privmsgs = nil
+	return out
+}
+
+// privmsgFrom builds a PRIVMSG from prefix to target, as the bot would receive it.
+func privmsgFrom(prefix, target, text string) ircmsg.Message {
+	return ircmsg.MakeMessage(nil, prefix, "PRIVMSG", target, text)
+}
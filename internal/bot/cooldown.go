@@ -0,0 +1,73 @@
+package bot
+
+import "time"
+
+type cooldownKey struct {
+	user    string
+	command string
+}
+
+type cooldownEntry struct {
+	until  time.Time
+	warned bool // whether or not the user has been told to wait during this window
+}
+
+// cooldownFor returns how long the user in ctx must wait between invocations of cmd, or 0 if they needn't. Admins are
+// exempt, as is everyone in local mode. CommandCooldown is read each time, so that reloading the config applies to it.
+func (b *Bot) cooldownFor(ctx *CommandContext, cmd *Command) time.Duration {
+	if !cmd.cooldown || b.local || b.isAdmin(ctx.Message) {
+		return 0
+	}
+
+	return b.cfg().CommandCooldown
+}
+
+// checkCooldown is a PreHook enforcing per user command cooldowns. A user invoking a command during its cooldown is
+// told once how long to wait, further attempts during the same window are silently dropped. Cooldowns only start once
+// a command has been run or queued, see startCooldown, so rejected invocations don't count.
+func (b *Bot) checkCooldown(ctx *CommandContext, cmd *Command, _ string, reply ReplyFunc) bool {
+	if b.cooldownFor(ctx, cmd) <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	key := cooldownKey{user: ctx.UserKey, command: cmd.name}
+
+	b.cooldownsMu.Lock()
+	defer b.cooldownsMu.Unlock()
+
+	for k, e := range b.cooldowns {
+		if now.After(e.until) {
+			delete(b.cooldowns, k)
+		}
+	}
+
+	entry, ok := b.cooldowns[key]
+	if !ok {
+		return true
+	}
+
+	if !entry.warned {
+		entry.warned = true
+		remaining := entry.until.Sub(now).Round(time.Second)
+		if remaining < time.Second {
+			remaining = time.Second
+		}
+
+		reply("Please wait %s before using %s%s again", remaining, b.cfg().prefixFor(ctx.Channel), cmd.name)
+	}
+
+	return false
+}
+
+// startCooldown starts the user in ctx's cooldown for cmd, which is about to run or has been queued.
+func (b *Bot) startCooldown(ctx *CommandContext, cmd *Command) {
+	cooldown := b.cooldownFor(ctx, cmd)
+	if cooldown <= 0 {
+		return
+	}
+
+	b.cooldownsMu.Lock()
+	defer b.cooldownsMu.Unlock()
+	b.cooldowns[cooldownKey{user: ctx.UserKey, command: cmd.name}] = &cooldownEntry{until: time.Now().Add(cooldown)}
+}
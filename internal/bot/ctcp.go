@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+const sourceURL = "https://github.com/A-UNDERSCORE-D/goplay-irc"
+
+// setupCTCP replaces the irc library's CTCP TIME and CLIENTINFO handlers, and adds one for SOURCE. The library only
+// registers its handlers when it first connects, so this must be called after that, and only once.
+func (b *Bot) setupCTCP() {
+	b.irc.ClearCallback("CTCP_TIME")
+	b.irc.AddCallback("CTCP_TIME", b.onCTCPTime)
+	b.irc.ClearCallback("CTCP_CLIENTINFO")
+	b.irc.AddCallback("CTCP_CLIENTINFO", b.onCTCPClientInfo)
+	b.irc.AddCallback("CTCP", b.onCTCP)
+}
+
+func (b *Bot) ctcpReply(prefix, command, text string) {
+	b.conn.SendRaw(fmt.Sprintf("NOTICE %s :\x01%s %s\x01", ircevent.ExtractNick(prefix), command, text))
+}
+
+// onCTCPTime replies with the bot's local time, rather than the library's default of UTC.
+func (b *Bot) onCTCPTime(msg ircmsg.Message) {
+	b.ctcpReply(msg.Prefix, "TIME", time.Now().Format(time.RFC1123Z))
+}
+
+func (b *Bot) onCTCPClientInfo(msg ircmsg.Message) {
+	b.ctcpReply(msg.Prefix, "CLIENTINFO", "PING VERSION TIME USERINFO CLIENTINFO SOURCE")
+}
+
+// onCTCP handles CTCP requests the library does not know about.
+func (b *Bot) onCTCP(msg ircmsg.Message) {
+	if len(msg.Params) == 2 && msg.Params[1] == "SOURCE" {
+		b.ctcpReply(msg.Prefix, "SOURCE", sourceURL)
+	}
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+)
+
+// knownError is an error the playground is known to produce, along with an explanation that is more useful to users
+// than the raw error.
+type knownError struct {
+	pattern     *regexp.Regexp
+	explanation string
+}
+
+const (
+	noNetworkExplanation  = "Network access is not available on the playground"
+	tooLargeExplanation   = "Program exceeds playground limits, try reducing the size of the code"
+	thirdPartyExplanation = "Third-party imports aren't supported on the playground (stdlib only)"
+)
+
+var knownErrors = []knownError{
+	// Sandbox network restrictions, as seen from the net package
+	{regexp.MustCompile(`(?i)dial (?:tcp|udp)[46]?\b.*: (?:permission denied|operation not permitted|protocol not available)`), noNetworkExplanation},
+	{regexp.MustCompile(`(?i)socket: (?:permission denied|operation not permitted|protocol not available)`), noNetworkExplanation},
+	{regexp.MustCompile(`(?i)lookup \S+(?: on \S+)?: (?:no such host|.*protocol not available)`), noNetworkExplanation},
+
+	// Size limits enforced by the playground backend
+	{regexp.MustCompile(`(?i)(?:program|snippet|source) (?:is )?too (?:long|large)`), tooLargeExplanation},
+	{regexp.MustCompile(`(?i)request entity too large`), tooLargeExplanation},
+
+	// Imports outside of the standard library, which cannot be resolved
+	{regexp.MustCompile(`cannot find package`), thirdPartyExplanation},
+	{regexp.MustCompile(`no required module provides package`), thirdPartyExplanation},
+	{regexp.MustCompile(`cannot find module providing package`), thirdPartyExplanation},
+	{regexp.MustCompile(`package \S+ is not in (?:GOROOT|std)`), thirdPartyExplanation},
+}
+
+// explainError checks s against the known playground errors, and returns the explanation for the first that matches.
+func explainError(s string) (string, bool) {
+	for _, e := range knownErrors {
+		if e.pattern.MatchString(s) {
+			return e.explanation, true
+		}
+	}
+
+	return "", false
+}
+
+// explainOrTrim returns the explanation for s if it is a known error, otherwise s is returned with surrounding
+// whitespace removed.
+func explainOrTrim(s string) string {
+	if explanation, ok := explainError(s); ok {
+		return explanation
+	}
+
+	return strings.TrimSpace(s)
+}
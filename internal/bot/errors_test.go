@@ -0,0 +1,72 @@
+package bot
+
+import "testing"
+
+// testExplanations checks that each of matching is explained as want, and that none of notMatching are explained.
+func testExplanations(t *testing.T, want string, matching, notMatching []string) {
+	t.Helper()
+
+	for _, s := range matching {
+		if got, ok := explainError(s); !ok || got != want {
+			t.Errorf("explainError(%q) = %q, %t; want %q", s, got, ok, want)
+		}
+	}
+
+	for _, s := range notMatching {
+		if got, ok := explainError(s); ok {
+			t.Errorf("explainError(%q) = %q, want no explanation", s, got)
+		}
+	}
+}
+
+func TestExplainErrorNetwork(t *testing.T) {
+	testExplanations(t, noNetworkExplanation, []string{
+		"dial tcp 93.184.216.34:80: connect: permission denied",
+		"dial tcp: lookup example.com on 8.8.8.8:53: dial udp 8.8.8.8:53: operation not permitted",
+		"dial tcp6 [::1]:80: socket: protocol not available",
+		"dial udp4 1.2.3.4:53: Operation not permitted",
+		"socket: permission denied",
+		"lookup example.com: no such host",
+		"lookup example.com on 127.0.0.1:53: write: protocol not available",
+	}, []string{
+		"dial tcp 127.0.0.1:80: connect: connection refused",
+		"permission denied",
+		"open /etc/shadow: permission denied",
+		"lookup table is empty",
+	})
+}
+
+func TestExplainErrorTooLarge(t *testing.T) {
+	testExplanations(t, tooLargeExplanation, []string{
+		"program too long",
+		"Program is too large to compile",
+		"snippet too large",
+		"unexpected response from playground: 413 Request Entity Too Large",
+	}, []string{
+		"too many open files",
+		"program exited: status 1",
+	})
+}
+
+func TestExplainErrorThirdParty(t *testing.T) {
+	testExplanations(t, thirdPartyExplanation, []string{
+		`prog.go:4:2: cannot find package "github.com/pkg/errors" in any of:`,
+		"prog.go:4:2: no required module provides package github.com/pkg/errors; to add it:",
+		"cannot find module providing package example.com/x",
+		"package example.com/x is not in GOROOT (/usr/local/go/src/example.com/x)",
+		"package foo/bar is not in std (/usr/local/go/src/foo/bar)",
+	}, []string{
+		"prog.go:4:2: imported and not used: \"os\"",
+		"prog.go:5:2: undefined: errors.Wrap",
+	})
+}
+
+func TestExplainOrTrim(t *testing.T) {
+	if got := explainOrTrim("  some error\n"); got != "some error" {
+		t.Errorf("explainOrTrim() = %q, want %q", got, "some error")
+	}
+
+	if got := explainOrTrim("socket: permission denied\n"); got != noNetworkExplanation {
+		t.Errorf("explainOrTrim() = %q, want %q", got, noNetworkExplanation)
+	}
+}
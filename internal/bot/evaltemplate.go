@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// defaultEvalTemplate wraps the code passed to ~eval in a main function, and is used when eval_template is not set.
+const defaultEvalTemplate = `package main
+
+func main() {
+	{{.Code}}
+}
+`
+
+// evalTemplateData is what eval_template is executed with.
+type evalTemplateData struct {
+	Code string // the code passed to ~eval, preceded by any setup needed by its flags
+}
+
+// parseEvalTemplate parses the eval_template config option, falling back to defaultEvalTemplate if it is empty. The
+// template is executed once here, so that templates that fail at execution time, such as by referring to fields that
+// don't exist, are caught on startup rather than on every eval.
+func parseEvalTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultEvalTemplate
+	}
+
+	tmpl, err := template.New("eval_template").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.Execute(io.Discard, evalTemplateData{}); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// executeEvalTemplate executes tmpl with code.
+func executeEvalTemplate(tmpl *template.Template, code string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, evalTemplateData{Code: code}); err != nil {
+		return "", fmt.Errorf("could not apply eval template: %w", err)
+	}
+
+	return buf.String(), nil
+}
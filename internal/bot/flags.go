@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// flagSpec declares a flag that a command accepts, as --name or, if it has a value, --name=value.
+type flagSpec struct {
+	name     string
+	hasValue bool
+}
+
+// parseFlags pulls any leading flags in accepted off of args, returning their values (empty for flags without one)
+// and the remaining arguments. Only flags at the start of args are parsed, so code containing -- later on is left
+// alone. Values may be double quoted, with Go escapes, to include spaces. A bare -- ends the flags early.
+func parseFlags(args string, accepted []flagSpec) (map[string]string, string, error) {
+	flags := make(map[string]string)
+	rest := strings.TrimLeft(args, " ")
+	for strings.HasPrefix(rest, "--") {
+		nameEnd := strings.IndexAny(rest[2:], "= ")
+		if nameEnd == -1 {
+			nameEnd = len(rest) - 2
+		}
+
+		name, after := rest[2:2+nameEnd], rest[2+nameEnd:]
+		if name == "" {
+			return flags, strings.TrimLeft(after, " "), nil
+		}
+
+		spec, ok := findFlag(accepted, name)
+		switch {
+		case !ok:
+			return nil, args, fmt.Errorf("unknown flag --%s", name)
+		case spec.hasValue && !strings.HasPrefix(after, "="):
+			return nil, args, fmt.Errorf("--%s requires a value", name)
+		case !spec.hasValue && strings.HasPrefix(after, "="):
+			return nil, args, fmt.Errorf("--%s does not take a value", name)
+		}
+
+		value := ""
+		if spec.hasValue {
+			var err error
+			value, after, err = parseFlagValue(after[1:])
+			if err != nil {
+				return nil, args, fmt.Errorf("invalid value for --%s: %w", name, err)
+			}
+		}
+
+		flags[name] = value
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	return flags, rest, nil
+}
+
+func findFlag(accepted []flagSpec, name string) (flagSpec, bool) {
+	for _, spec := range accepted {
+		if spec.name == name {
+			return spec, true
+		}
+	}
+
+	return flagSpec{}, false
+}
+
+// parseFlagValue parses a flag value from the start of s, returning it and whatever follows it.
+func parseFlagValue(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		if idx := strings.IndexByte(s, ' '); idx != -1 {
+			return s[:idx], s[idx:], nil
+		}
+
+		return s, "", nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			if i+1 < len(s) && s[i+1] != ' ' {
+				return "", s, errors.New("unexpected characters after closing quote")
+			}
+
+			value, err := strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+
+	return "", s, errors.New("unterminated quote")
+}
+
+// flagValue returns a pointer to the value of the named flag, or nil if it was not given.
+func flagValue(flags map[string]string, name string) *string {
+	value, ok := flags[name]
+	if !ok {
+		return nil
+	}
+
+	return &value
+}
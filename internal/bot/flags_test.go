@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNoImportsFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		want     bool
+		wantRest string
+	}{
+		{name: "absent", args: `fmt.Println("hi")`, wantRest: `fmt.Println("hi")`},
+		{name: "alone", args: `--no-imports fmt.Println("hi")`, want: true, wantRest: `fmt.Println("hi")`},
+		{name: "after other flags", args: `--seed=1 --time --no-imports x`, want: true, wantRest: "x"},
+		{name: "before other flags", args: `--no-imports --seed=1 x`, want: true, wantRest: "x"},
+		{name: "leading spaces", args: `   --no-imports   x`, want: true, wantRest: "x"},
+		{name: "after code", args: `x --no-imports`, wantRest: "x --no-imports"},
+		{name: "after --", args: `-- --no-imports x`, wantRest: "--no-imports x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, rest, err := parseFlags(tt.args, evalFlags)
+			if err != nil {
+				t.Fatalf("parseFlags() error = %v", err)
+			}
+
+			if _, got := flags["no-imports"]; got != tt.want {
+				t.Errorf("parseFlags() no-imports = %t, want %t", got, tt.want)
+			}
+
+			if rest != tt.wantRest {
+				t.Errorf("parseFlags() rest = %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+
+	if _, _, err := parseFlags("--no-imports=yes x", evalFlags); err == nil {
+		t.Error("parseFlags() with a value for --no-imports succeeded, want error")
+	}
+}
+
+// parseFlagsCase is a test case for parseFlags.
+type parseFlagsCase struct {
+	name      string
+	args      string
+	wantFlags map[string]string
+	wantRest  string
+	wantErr   bool
+}
+
+func runParseFlagsCases(t *testing.T, accepted []flagSpec, tests []parseFlagsCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, rest, err := parseFlags(tt.args, accepted)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags(%q) error = %v, want error %t", tt.args, err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if rest != tt.args {
+					t.Errorf("parseFlags(%q) rest after error = %q, want the args unchanged", tt.args, rest)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(flags, tt.wantFlags) {
+				t.Errorf("parseFlags(%q) flags = %q, want %q", tt.args, flags, tt.wantFlags)
+			}
+
+			if rest != tt.wantRest {
+				t.Errorf("parseFlags(%q) rest = %q, want %q", tt.args, rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	accepted := []flagSpec{{name: "stdin", hasValue: true}, {name: "time"}}
+	none := map[string]string{}
+
+	runParseFlagsCases(t, accepted, []parseFlagsCase{
+		{name: "empty", args: "", wantFlags: none},
+		{name: "no flags", args: "fmt.Println(1)", wantFlags: none, wantRest: "fmt.Println(1)"},
+		{name: "flags only", args: "--time", wantFlags: map[string]string{"time": ""}},
+		{name: "bare value", args: "--stdin=abc x", wantFlags: map[string]string{"stdin": "abc"}, wantRest: "x"},
+		{name: "empty value", args: "--stdin= x", wantFlags: map[string]string{"stdin": ""}, wantRest: "x"},
+		{name: "quoted value", args: `--stdin="a b" x`, wantFlags: map[string]string{"stdin": "a b"}, wantRest: "x"},
+		{
+			name: "escapes", args: `--stdin="a\n\"b\"" x`,
+			wantFlags: map[string]string{"stdin": "a\n\"b\""}, wantRest: "x",
+		},
+		{name: "quoted --", args: `--stdin="--time" x`, wantFlags: map[string]string{"stdin": "--time"}, wantRest: "x"},
+		{name: "quoted value at end", args: `--stdin="a b"`, wantFlags: map[string]string{"stdin": "a b"}},
+		{name: "repeated", args: "--stdin=a --stdin=b x", wantFlags: map[string]string{"stdin": "b"}, wantRest: "x"},
+		{
+			name: "end of flags", args: "--time -- --stdin=a",
+			wantFlags: map[string]string{"time": ""}, wantRest: "--stdin=a",
+		},
+
+		// Only leading flags count, so -- in the code itself is left alone
+		{
+			name: "-- in a string literal", args: `fmt.Println("--time")`,
+			wantFlags: none, wantRest: `fmt.Println("--time")`,
+		},
+		{
+			name: "flag then -- in a string literal", args: `--time fmt.Println("--stdin=x", "--")`,
+			wantFlags: map[string]string{"time": ""}, wantRest: `fmt.Println("--stdin=x", "--")`,
+		},
+		{name: "decrement", args: "x--; fmt.Println(x)", wantFlags: none, wantRest: "x--; fmt.Println(x)"},
+		{name: "-- in a comment", args: "x := 1 // --time", wantFlags: none, wantRest: "x := 1 // --time"},
+
+		{name: "unknown", args: "--nope x", wantErr: true},
+		{name: "missing value", args: "--stdin x", wantErr: true},
+		{name: "unexpected value", args: "--time=1 x", wantErr: true},
+		{name: "unterminated quote", args: `--stdin="a b x`, wantErr: true},
+		{name: "text after quote", args: `--stdin="a"b x`, wantErr: true},
+		{name: "bad escape", args: `--stdin="\q" x`, wantErr: true},
+	})
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatterLocal formats code in process, with goimports
+	FormatterLocal = "local"
+	// FormatterPlayground formats code with the playground's /fmt endpoint, so that the result is exactly what the
+	// playground itself would produce
+	FormatterPlayground = "playground"
+)
+
+// fmtResponse is the response from the playground's /fmt endpoint
+type fmtResponse struct {
+	Body  string
+	Error string
+}
+
+// format runs gofmt over code, additionally resolving imports if requested, using the configured formatter.
+func (b *Bot) format(ctx context.Context, code []byte, doImports bool) ([]byte, error) {
+	if b.cfg().Formatter != FormatterPlayground {
+		return formatCode(code, doImports)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg().CompileTimeout)
+	defer cancel()
+
+	var out fmtResponse
+	err := b.callPlayground(ctx, func() error { return b.postFmt(ctx, code, doImports, &out) })
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, errors.New("formatting timed out")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if out.Error != "" {
+		return nil, fmt.Errorf("could not format / imports source: %s", out.Error)
+	}
+
+	return []byte(out.Body), nil
+}
+
+// postFmt sends code to the playground's /fmt endpoint, decoding the response into out.
+func (b *Bot) postFmt(ctx context.Context, code []byte, doImports bool, out *fmtResponse) error {
+	form := url.Values{"body": {string(code)}, "imports": {strconv.FormatBool(doImports)}}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, defaultPlayground+"/fmt", strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error from playground: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from playground: %s", res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode fmt response: %w", err)
+	}
+
+	return nil
+}
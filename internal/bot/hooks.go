@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// PreHook is run before a command is dispatched. If any PreHook returns false, the command is not run, and the
+	// hook is responsible for replying to the user if needed.
+	PreHook func(ctx *CommandContext, cmd *Command, args string, reply ReplyFunc) bool
+	// PostHook is run after a command's callback has returned, with what it did.
+	PostHook func(ctx *CommandContext, cmd *Command, args string, result CommandResult)
+)
+
+// CommandResult describes how a command went, for PostHooks.
+type CommandResult struct {
+	Elapsed time.Duration
+	Replies []string // Everything the command replied, in order, before it was attributed and split into messages
+	Err     error    // Set if the callback called CommandContext.Fail, or else by the first reply that couldn't be sent
+}
+
+// AddPreHook registers a hook to be run before every command. Hooks are run in the order they were added.
+func (b *Bot) AddPreHook(hook PreHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.preHooks = append(b.preHooks, hook)
+}
+
+// AddPostHook registers a hook to be run after every command. Hooks are run in the order they were added.
+func (b *Bot) AddPostHook(hook PostHook) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.postHooks = append(b.postHooks, hook)
+}
+
+// runPreHooks runs all registered PreHooks, stopping at the first that vetoes the command.
+func (b *Bot) runPreHooks(ctx *CommandContext, cmd *Command, args string, reply ReplyFunc) bool {
+	b.hooksMu.RLock()
+	hooks := b.preHooks
+	b.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		if !hook(ctx, cmd, args, reply) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *Bot) runPostHooks(ctx *CommandContext, cmd *Command, args string, result CommandResult) {
+	b.hooksMu.RLock()
+	hooks := b.postHooks
+	b.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, cmd, args, result)
+	}
+}
+
+// runCommand runs cmd's callback, followed by any PostHooks. The callback's replies are recorded for the hooks as
+// they are sent.
+func (b *Bot) runCommand(ctx *CommandContext, cmd *Command, args string, reply ReplyFunc) {
+	var (
+		mu      sync.Mutex
+		result  CommandResult
+		sendErr error
+	)
+
+	recorded := func(s string, a ...interface{}) error {
+		if len(a) != 0 {
+			s = fmt.Sprintf(s, a...)
+		}
+
+		err := reply(s)
+
+		mu.Lock()
+		defer mu.Unlock()
+		result.Replies = append(result.Replies, s)
+		if sendErr == nil {
+			sendErr = err
+		}
+
+		return err
+	}
+
+	start := time.Now()
+	cmd.callback(ctx, args, recorded)
+	result.Elapsed = time.Since(start)
+
+	mu.Lock()
+	result.Err = ctx.err
+	if result.Err == nil {
+		result.Err = sendErr
+	}
+	mu.Unlock()
+
+	b.runPostHooks(ctx, cmd, args, result)
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPreHookVeto(t *testing.T) {
+	b, f := newDispatchBot(t, nil)
+
+	var calls []string
+	b.AddPreHook(func(_ *CommandContext, cmd *Command, args string, reply ReplyFunc) bool {
+		calls = append(calls, "first "+cmd.Name())
+		if args == "blocked" {
+			reply("Vetoed")
+			return false
+		}
+
+		return true
+	})
+
+	b.AddPreHook(func(_ *CommandContext, cmd *Command, _ string, _ ReplyFunc) bool {
+		calls = append(calls, "second "+cmd.Name())
+		return true
+	})
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo hi"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) echo: hi"})
+
+	if want := []string{"first echo", "second echo"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hooks called %q, want %q", calls, want)
+	}
+
+	// A veto stops both the command and any later hooks
+	calls = nil
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo blocked"))
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) Vetoed"})
+
+	if want := []string{"first echo"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hooks called %q, want %q", calls, want)
+	}
+}
+
+func TestPostHook(t *testing.T) {
+	b, _ := newDispatchBot(t, nil)
+
+	errFailed := errors.New("failed")
+	b.createCommand("fail", false, false, func(ctx *CommandContext, _ string, reply ReplyFunc) {
+		ctx.Fail(errFailed)
+		reply("Something went wrong")
+	}, "Fails")
+
+	type call struct {
+		nick, command, args string
+		replies             []string
+		err                 error
+	}
+
+	var calls []call
+	b.AddPostHook(func(ctx *CommandContext, cmd *Command, args string, result CommandResult) {
+		if result.Elapsed < 0 {
+			t.Errorf("post hook elapsed = %s, want >= 0", result.Elapsed)
+		}
+
+		calls = append(calls, call{ctx.Nick, cmd.Name(), args, result.Replies, result.Err})
+	})
+
+	b.AddPreHook(func(_ *CommandContext, _ *Command, args string, _ ReplyFunc) bool {
+		return args != "blocked"
+	})
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo hi"))
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~echo blocked"))
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~fail"))
+
+	want := []call{
+		{"user", "echo", "hi", []string{"echo: hi"}, nil},
+		{"user", "fail", "", []string{"Something went wrong"}, errFailed},
+	}
+
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("post hook calls = %+v, want %+v", calls, want)
+	}
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// userAgent identifies the bot in HTTP requests, so that the playground's operators know who to contact.
+func userAgent() string {
+	return "goplay-irc/" + Version + " (+" + sourceURL + ")"
+}
+
+// userAgentTransport sets the User-Agent header on all requests, before passing them on to the wrapped transport.
+type userAgentTransport struct {
+	wrapped http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent())
+	return t.wrapped.RoundTrip(req)
+}
+
+// newHTTPClient returns the client shared by everything the bot requests over HTTP. Requests go through proxy if it
+// is not nil, and are given up to timeout to complete.
+func newHTTPClient(proxy *url.URL, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: &userAgentTransport{wrapped: transport}, Timeout: timeout}
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ignore is a single entry in the ignore list.
+type ignore struct {
+	mask    string
+	re      *regexp.Regexp
+	expires time.Time // zero for ignores that never expire
+}
+
+func (i *ignore) expired(now time.Time) bool {
+	return !i.expires.IsZero() && now.After(i.expires)
+}
+
+// addIgnore ignores all users matching mask. If duration is non-zero, the ignore expires after it. Adding a mask that
+// is already ignored replaces its expiry.
+func (b *Bot) addIgnore(mask string, duration time.Duration) error {
+	re, err := compileMask(mask)
+	if err != nil {
+		return err
+	}
+
+	entry := &ignore{mask: mask, re: re}
+	if duration > 0 {
+		entry.expires = time.Now().Add(duration)
+	}
+
+	b.ignoresMu.Lock()
+	defer b.ignoresMu.Unlock()
+	b.ignores[strings.ToLower(mask)] = entry
+
+	return nil
+}
+
+// updateIgnores removes any masks in previous that are not in current from the ignore list, and adds any that are new
+// in current. The masks in current must be valid.
+func (b *Bot) updateIgnores(previous, current []string) {
+	for _, mask := range previous {
+		if !containsFold(current, mask) {
+			b.removeIgnore(mask)
+		}
+	}
+
+	for _, mask := range current {
+		if !containsFold(previous, mask) {
+			b.addIgnore(mask, 0)
+		}
+	}
+}
+
+// removeIgnore removes mask from the ignore list, returning whether or not it was there.
+func (b *Bot) removeIgnore(mask string) bool {
+	b.ignoresMu.Lock()
+	defer b.ignoresMu.Unlock()
+
+	key := strings.ToLower(mask)
+	_, ok := b.ignores[key]
+	delete(b.ignores, key)
+
+	return ok
+}
+
+// isIgnored returns whether or not prefix matches any unexpired ignore. Expired ignores are removed.
+func (b *Bot) isIgnored(prefix string) bool {
+	b.ignoresMu.Lock()
+	defer b.ignoresMu.Unlock()
+	b.sweepIgnoresLocked()
+
+	for _, i := range b.ignores {
+		if i.re.MatchString(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sweepIgnoresLocked removes any expired ignores. ignoresMu must be held.
+func (b *Bot) sweepIgnoresLocked() {
+	now := time.Now()
+	for key, i := range b.ignores {
+		if i.expired(now) {
+			delete(b.ignores, key)
+		}
+	}
+}
+
+// describeIgnores returns a description of each current ignore, sorted by mask.
+func (b *Bot) describeIgnores() []string {
+	b.ignoresMu.Lock()
+	defer b.ignoresMu.Unlock()
+	b.sweepIgnoresLocked()
+
+	out := make([]string, 0, len(b.ignores))
+	for _, i := range b.ignores {
+		if i.expires.IsZero() {
+			out = append(out, i.mask)
+			continue
+		}
+
+		remaining := time.Until(i.expires).Round(time.Second)
+		out = append(out, fmt.Sprintf("%s (%s remaining)", i.mask, remaining))
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+// IgnoresCmd is the callback for the ~ignores IRC command, and lists the current ignores.
+func (b *Bot) IgnoresCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	ignores := b.describeIgnores()
+	if len(ignores) == 0 {
+		reply("Nobody is ignored")
+		return
+	}
+
+	reply("Ignored: %s", strings.Join(ignores, ", "))
+}
+
+// IgnoreCmd is the callback for the ~ignore IRC command. It ignores the given mask, optionally for a duration.
+func (b *Bot) IgnoreCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	split := strings.Fields(args)
+	if len(split) == 0 || len(split) > 2 {
+		reply("Usage: ignore <nick!user@host> [duration]")
+		return
+	}
+
+	var duration time.Duration
+	if len(split) == 2 {
+		d, err := time.ParseDuration(split[1])
+		if err != nil || d <= 0 {
+			reply("Invalid duration %q", split[1])
+			return
+		}
+
+		duration = d
+	}
+
+	if err := b.addIgnore(split[0], duration); err != nil {
+		reply("Invalid mask: %s", err)
+		return
+	}
+
+	if duration > 0 {
+		reply("Ignoring %s for %s", split[0], duration)
+		return
+	}
+
+	reply("Ignoring %s", split[0])
+}
+
+// UnignoreCmd is the callback for the ~unignore IRC command, and removes the given mask from the ignore list.
+func (b *Bot) UnignoreCmd(_ *CommandContext, args string, reply ReplyFunc) {
+	mask := strings.TrimSpace(args)
+	if mask == "" {
+		reply("Usage: unignore <nick!user@host>")
+		return
+	}
+
+	if !b.removeIgnore(mask) {
+		reply("%s is not ignored", mask)
+		return
+	}
+
+	reply("No longer ignoring %s", mask)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// importLines returns the imports in code, formatted as they would appear in an import block, eg `f "fmt"`.
+func importLines(code []byte) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "prog.go", code, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(f.Imports))
+	for _, spec := range f.Imports {
+		line := spec.Path.Value
+		if spec.Name != nil {
+			line = spec.Name.Name + " " + line
+		}
+
+		out = append(out, line)
+	}
+
+	return out, nil
+}
+
+// diffImports returns the imports in after that are not in before, and those in before that are not in after.
+func diffImports(before, after []string) (added, removed []string) {
+	for _, imp := range after {
+		if !containsFold(before, imp) {
+			added = append(added, imp)
+		}
+	}
+
+	for _, imp := range before {
+		if !containsFold(after, imp) {
+			removed = append(removed, imp)
+		}
+	}
+
+	return added, removed
+}
+
+// ImportsCmd is the callback for the ~imports IRC command. It reports the imports that resolving imports would add
+// to or remove from the given snippets, without changing them.
+func (b *Bot) ImportsCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	b.forEachSnippet(args, reply, func(code string) string {
+		before, err := importLines([]byte(code))
+		if err != nil {
+			return "Unable to parse snippet: " + err.Error()
+		}
+
+		formatted, err := b.format(context.Background(), []byte(code), true)
+		if err != nil {
+			return "Unable to resolve imports: " + strings.TrimSpace(err.Error())
+		}
+
+		after, err := importLines(formatted)
+		if err != nil {
+			return "Unable to parse snippet: " + err.Error()
+		}
+
+		added, removed := diffImports(before, after)
+		if len(added) == 0 && len(removed) == 0 {
+			return "imports already correct"
+		}
+
+		var parts []string
+		if len(added) > 0 {
+			parts = append(parts, "added "+strings.Join(added, ", "))
+		}
+
+		if len(removed) > 0 {
+			parts = append(parts, "removed "+strings.Join(removed, ", "))
+		}
+
+		summary := strings.Join(parts, "; ")
+		if !b.wouldTruncate(ctx, summary) {
+			return summary
+		}
+
+		// Too long to reply with, so paste it as a diff instead
+		var diff strings.Builder
+		for _, imp := range added {
+			fmt.Fprintf(&diff, "+\t%s\n", imp)
+		}
+
+		for _, imp := range removed {
+			fmt.Fprintf(&diff, "-\t%s\n", imp)
+		}
+
+		link, err := b.paste(diff.String())
+		if err != nil {
+			return summary
+		}
+
+		return fmt.Sprintf("%d imports added, %d removed: %s", len(added), len(removed), link)
+	})
+}
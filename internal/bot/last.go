@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"strings"
+	"time"
+)
+
+// lastResult is the most recent successful ~eval in a channel, for ~last.
+type lastResult struct {
+	nick    string
+	share   string
+	summary string
+	at      time.Time
+}
+
+// recordLast stores the result of a successful ~eval in target, replacing any earlier one.
+func (b *Bot) recordLast(target, nick, share, summary string) {
+	b.lastResultsMu.Lock()
+	defer b.lastResultsMu.Unlock()
+	b.lastResults[strings.ToLower(target)] = &lastResult{nick: nick, share: share, summary: summary, at: time.Now()}
+}
+
+// lastFor returns the most recent successful ~eval in target, if there is one newer than last_result_ttl.
+func (b *Bot) lastFor(target string) (*lastResult, bool) {
+	b.lastResultsMu.Lock()
+	defer b.lastResultsMu.Unlock()
+
+	key := strings.ToLower(target)
+	last, ok := b.lastResults[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Since(last.at) > b.cfg().LastResultTTL {
+		delete(b.lastResults, key)
+		return nil, false
+	}
+
+	return last, true
+}
+
+// LastCmd is the callback for the ~last IRC command, and responds with the share link and result of the most recent
+// successful ~eval where it was invoked.
+func (b *Bot) LastCmd(ctx *CommandContext, _ string, reply ReplyFunc) {
+	last, ok := b.lastFor(ctx.Target)
+	if !ok {
+		reply("Nothing has been evaluated here recently")
+		return
+	}
+
+	ago := humanDuration(time.Since(last.at))
+	if last.share == "" {
+		reply("%s ago by %s: %s", ago, last.nick, last.summary)
+		return
+	}
+
+	reply("%s ago by %s: %s : %s", ago, last.nick, last.share, last.summary)
+}
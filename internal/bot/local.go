@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+// In local mode, all input appears to come from this user, in this channel. Add the prefix to admins to use admin
+// commands locally.
+const (
+	localPrefix  = "local!local@localhost"
+	localChannel = "#local"
+)
+
+// localConn is an ircConn that writes everything the bot would send to an io.Writer, rather than to a server.
+type localConn struct {
+	nick string
+	out  io.Writer
+}
+
+func (l *localConn) CurrentNick() string         { return l.nick }
+func (l *localConn) ISupport() map[string]string { return nil }
+func (l *localConn) Connected() bool             { return false }
+func (l *localConn) Join(channel string) error   { return l.Send("JOIN", channel) }
+
+func (l *localConn) Privmsg(target, msg string) error {
+	_, err := fmt.Fprintf(l.out, "[%s] %s\n", target, msg)
+	return err
+}
+
+func (l *localConn) Send(command string, params ...string) error {
+	return l.SendRaw(strings.TrimSpace(command + " " + strings.Join(params, " ")))
+}
+
+func (l *localConn) SendRaw(message string) error {
+	_, err := fmt.Fprintf(l.out, "-> %s\n", message)
+	return err
+}
+
+// RunLocal runs the bot without connecting to IRC, treating each line read from in as a message in localChannel and
+// writing replies to out. It returns once in is exhausted and all commands have completed, and is intended for
+// trying out commands during development, so neither cooldowns nor the reply throttle apply.
+func (b *Bot) RunLocal(in io.Reader, out io.Writer) error {
+	b.conn = &localConn{nick: b.cfg().Nick, out: out}
+	b.local = true
+	b.throttle = nil
+	b.startWorkers()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		b.onPrivmsg(ircmsg.MakeMessage(nil, localPrefix, "PRIVMSG", localChannel, scanner.Text()))
+	}
+
+	b.inFlight.Wait()
+	return scanner.Err()
+}
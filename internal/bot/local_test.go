@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunLocalSkipsCooldownAndThrottle(t *testing.T) {
+	b, _ := newDispatchBot(t, func(c *BotConfig) {
+		c.MessagesPerSecond = 0.1
+		c.MessageBurst = 1
+		c.CommandCooldown = time.Minute
+	}, withCooldown())
+
+	var out bytes.Buffer
+	start := time.Now()
+	if err := b.RunLocal(strings.NewReader("~echo 1\n~echo 2\n~echo 3\n"), &out); err != nil {
+		t.Fatalf("RunLocal() error = %v", err)
+	}
+
+	want := "[#local] (local) echo: 1\n[#local] (local) echo: 2\n[#local] (local) echo: 3\n"
+	if out.String() != want {
+		t.Errorf("RunLocal() wrote %q, want %q", out.String(), want)
+	}
+
+	// At 0.1 messages per second, throttled replies would take 20s
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunLocal() took %s, want replies not to be throttled", elapsed)
+	}
+}
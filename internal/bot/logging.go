@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel is the minimum severity of messages that are logged.
+type LogLevel int32
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[LogLevel]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("LogLevel(%d)", int32(l))
+}
+
+// ParseLogLevel parses a level name, such as "warn", case insensitively.
+func ParseLogLevel(s string) (LogLevel, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+var currentLevel = int32(LevelInfo)
+
+// SetLogLevel sets the minimum level of messages that are logged.
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&currentLevel, int32(level))
+}
+
+// output logs msg at level through the standard logger, keeping its flags (and thus source annotations). depth is
+// the number of frames between output and the caller whose location should be logged.
+func output(depth int, level LogLevel, msg string) {
+	if int32(level) < atomic.LoadInt32(&currentLevel) {
+		return
+	}
+
+	log.Output(depth+1, level.String()+" "+msg)
+}
+
+// logf formats and logs a message at level, annotated with the location of the caller of debugf and friends.
+func logf(level LogLevel, format string, args ...interface{}) {
+	output(3, level, fmt.Sprintf(format, args...))
+}
+
+func debugf(format string, args ...interface{}) { logf(LevelDebug, format, args...) }
+func infof(format string, args ...interface{})  { logf(LevelInfo, format, args...) }
+func warnf(format string, args ...interface{})  { logf(LevelWarn, format, args...) }
+func errorf(format string, args ...interface{}) { logf(LevelError, format, args...) }
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileMask converts a nick!user@host glob mask into a case insensitive regexp. `*` matches any number of
+// characters, and `?` matches exactly one.
+func compileMask(mask string) (*regexp.Regexp, error) {
+	if err := validateMask(mask); err != nil {
+		return nil, err
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("(?i)^")
+	for _, r := range mask {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteByte('$')
+
+	return regexp.Compile(sb.String())
+}
+
+// validateMask checks that mask is of the form nick!user@host.
+func validateMask(mask string) error {
+	bang := strings.IndexByte(mask, '!')
+	at := strings.LastIndexByte(mask, '@')
+	if bang < 1 || at < bang+2 || at == len(mask)-1 || strings.ContainsAny(mask, " ,") {
+		return fmt.Errorf("invalid mask %q: must be of the form nick!user@host", mask)
+	}
+
+	return nil
+}
+
+// compileMasks compiles each of masks with compileMask. Invalid masks are left out, as they could never match.
+func compileMasks(masks []string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(masks))
+	for _, mask := range masks {
+		if re, err := compileMask(mask); err == nil {
+			out = append(out, re)
+		}
+	}
+
+	return out
+}
+
+// mergeMasks appends any masks in extra that are not already in masks, ignoring case. Duplicates within extra are
+// also removed.
+func mergeMasks(masks, extra []string) []string {
+	seen := make(map[string]bool, len(masks)+len(extra))
+	out := make([]string, 0, len(masks)+len(extra))
+	for _, m := range append(append([]string{}, masks...), extra...) {
+		key := strings.ToLower(m)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		out = append(out, m)
+	}
+
+	return out
+}
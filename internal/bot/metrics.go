@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Results of a compile, used as the result label on goplay_compiles_total
+const (
+	compileOK           = "ok"            // The program compiled, and was run
+	compileFailed       = "compile_error" // The program did not compile
+	compileRequestError = "error"         // The playground could not be reached, or did not respond in time
+)
+
+// Upper bounds of the compile latency histogram buckets, in seconds
+var latencyBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metrics records usage of the bot, and exposes it in the Prometheus text format. There is deliberately no
+// dependency on the Prometheus client library, as only a handful of simple metrics are needed.
+type metrics struct {
+	mu       sync.Mutex
+	commands map[string]uint64 // keyed by command name
+	compiles map[string]uint64 // keyed by compile result
+
+	latencyCounts []uint64 // per bucket in latencyBuckets, non-cumulative
+	latencySum    float64
+	latencyCount  uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		commands:      make(map[string]uint64),
+		compiles:      make(map[string]uint64),
+		latencyCounts: make([]uint64, len(latencyBuckets)),
+	}
+}
+
+// commandRun records an invocation of the named command.
+func (m *metrics) commandRun(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands[name]++
+}
+
+// compileDone records a round trip to the playground that took elapsed, with the given result.
+func (m *metrics) compileDone(result string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compiles[result]++
+
+	seconds := elapsed.Seconds()
+	m.latencySum += seconds
+	m.latencyCount++
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			m.latencyCounts[i]++
+			break
+		}
+	}
+}
+
+// writeCounter writes a counter with a single label, sorted by label value so that output is stable.
+func writeCounter(w io.Writer, name, help, label string, values map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, k, values[k])
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &strings.Builder{}
+	writeCounter(out, "goplay_commands_total", "Commands run, by command.", "command", m.commands)
+	writeCounter(out, "goplay_compiles_total", "Requests to compile code on the playground, by result.", "result", m.compiles)
+
+	const latency = "goplay_compile_duration_seconds"
+	fmt.Fprintf(out, "# HELP %s Round trip time of requests to compile code on the playground.\n", latency)
+	fmt.Fprintf(out, "# TYPE %s histogram\n", latency)
+
+	var cumulative uint64
+	for i, bound := range latencyBuckets {
+		cumulative += m.latencyCounts[i]
+		fmt.Fprintf(out, "%s_bucket{le=\"%g\"} %d\n", latency, bound, cumulative)
+	}
+
+	fmt.Fprintf(out, "%s_bucket{le=\"+Inf\"} %d\n", latency, m.latencyCount)
+	fmt.Fprintf(out, "%s_sum %g\n%s_count %d\n", latency, m.latencySum, latency, m.latencyCount)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	io.WriteString(w, out.String())
+}
+
+// serveMetrics serves metrics on addr at /metrics. It only returns if the server fails.
+func (b *Bot) serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", b.metrics)
+	infof("Serving metrics on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		errorf("Metrics server failed: %s", err)
+	}
+}
@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// NetworkConfig is the config for one of several networks the bot connects to at once. Any field left unset is taken
+// from the top level of BotConfig; lists replace those at the top level, rather than adding to them, though admins from
+// BOT_EXTRA_ADMINS are added to every network's.
+type NetworkConfig struct {
+	Name   string `toml:"name"` // used in logs, and to name the network's state file
+	Server string `toml:"server"`
+	UseTLS *bool  `toml:"use_tls"`
+
+	Nick             string `toml:"nick"`
+	SASLUser         string `toml:"sasl_user"`
+	SASLPassword     string `toml:"sasl_password"`
+	NickServPassword string `toml:"nickserv_password"`
+	NickServUser     string `toml:"nickserv_user"`
+
+	Admins          []string `toml:"admins"` // hostmasks differ between networks, so these usually need setting
+	JoinChannels    []string `toml:"join_channels"`
+	AllowedChannels []string `toml:"allowed_channels"`
+
+	// StateFile defaults to the top level state_file, with the network's name added, eg state-libera.toml
+	StateFile string `toml:"state_file"`
+}
+
+// forNetwork returns a copy of c with the settings from n applied, for the Bot connected to that network.
+func (c *BotConfig) forNetwork(n NetworkConfig) *BotConfig {
+	out := *c
+	out.Networks = nil
+	out.network = n.Name
+
+	set := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+
+	set(&out.Server, n.Server)
+	set(&out.Nick, n.Nick)
+	set(&out.SASLUser, n.SASLUser)
+	set(&out.SASLPassword, n.SASLPassword)
+	set(&out.NickServPassword, n.NickServPassword)
+	set(&out.NickServUser, n.NickServUser)
+
+	if n.UseTLS != nil {
+		out.UseTLS = *n.UseTLS
+	}
+
+	if n.Admins != nil {
+		out.Admins = n.Admins
+	}
+
+	if n.JoinChannels != nil {
+		out.JoinChannels = n.JoinChannels
+	}
+
+	if n.AllowedChannels != nil {
+		out.AllowedChannels = n.AllowedChannels
+	}
+
+	out.StateFile = n.StateFile
+	if out.StateFile == "" {
+		base := c.StateFile
+		if base == "" {
+			base = defaultStateFile
+		}
+
+		ext := filepath.Ext(base)
+		out.StateFile = strings.TrimSuffix(base, ext) + "-" + n.Name + ext
+	}
+
+	return &out
+}
+
+// loadConfig loads the config at path, narrowed down to the network b is connected to, if it is one of several.
+func (b *Bot) loadConfig(path string) (*BotConfig, error) {
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := b.cfg().network
+	if name == "" {
+		if len(loaded.Networks) > 0 {
+			return nil, errors.New("networks cannot be added without a restart")
+		}
+
+		return loaded, nil
+	}
+
+	for _, n := range loaded.Networks {
+		if strings.EqualFold(n.Name, name) {
+			return loaded.forNetwork(n), nil
+		}
+	}
+
+	return nil, fmt.Errorf("network %q is no longer in the config", name)
+}
+
+// Group is a set of bots, one for each network in BotConfig.Networks, run from a single process. Each has its own
+// connection, channels, and credentials, so replies always go to the network a command came from, but the first bot's
+// workers, playground client, result cache, paster, and metrics are shared by all of them.
+type Group struct {
+	bots []*Bot
+}
+
+// NewGroup creates a bot for each network in c. If c has no networks, the group contains a single bot, as from New.
+func NewGroup(c *BotConfig) (*Group, error) {
+	if len(c.Networks) == 0 {
+		b, err := New(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Group{bots: []*Bot{b}}, nil
+	}
+
+	g := &Group{}
+	names := make(map[string]bool)
+	stateFiles := make(map[string]string)
+	for _, n := range c.Networks {
+		if n.Name == "" {
+			return nil, errors.New("every network must have a name")
+		}
+
+		if names[strings.ToLower(n.Name)] {
+			return nil, fmt.Errorf("duplicate network %q", n.Name)
+		}
+
+		names[strings.ToLower(n.Name)] = true
+
+		nc := c.forNetwork(n)
+		if nc.Server == "" {
+			return nil, fmt.Errorf("network %q has no server", n.Name)
+		}
+
+		if other, ok := stateFiles[nc.StateFile]; ok {
+			return nil, fmt.Errorf("networks %q and %q have the same state_file", other, n.Name)
+		}
+
+		stateFiles[nc.StateFile] = n.Name
+
+		var primary *Bot
+		if len(g.bots) > 0 {
+			primary = g.bots[0]
+		}
+
+		b, err := newBot(nc, primary)
+		if err != nil {
+			return nil, fmt.Errorf("network %q: %w", n.Name, err)
+		}
+
+		g.bots = append(g.bots, b)
+	}
+
+	return g, nil
+}
+
+// Bots returns the bots in the group, the first of which is the one the others share with.
+func (g *Group) Bots() []*Bot {
+	return g.bots
+}
+
+// Run connects to every network, and blocks until all of the bots have stopped. A bot that gives up reconnecting
+// stops without affecting the others, and an error is only returned if every bot gave up.
+func (g *Group) Run() error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+		ok    bool
+	)
+
+	for _, b := range g.bots {
+		wg.Add(1)
+		go func(b *Bot) {
+			defer wg.Done()
+			err := b.Run()
+			if err != nil && len(g.bots) > 1 {
+				errorf("%s", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				ok = true
+			} else if first == nil {
+				first = err
+			}
+		}(b)
+	}
+
+	wg.Wait()
+	if ok {
+		return nil
+	}
+
+	return first
+}
+
+// Stop stops every bot in the group, see Bot.Stop.
+func (g *Group) Stop(reason string) {
+	var wg sync.WaitGroup
+	for _, b := range g.bots {
+		wg.Add(1)
+		go func(b *Bot) {
+			defer wg.Done()
+			b.Stop(reason)
+		}(b)
+	}
+
+	wg.Wait()
+}
+
+// Reload reloads the config of every bot in the group, see Bot.Reload. All of the bots are reloaded even if some
+// fail, and the first error is returned.
+func (g *Group) Reload(path string) error {
+	var first error
+	for _, b := range g.bots {
+		if err := b.Reload(path); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// share makes b use primary's workers, and everything it uses to talk to the playground, so that limits like workers
+// and max_concurrent_compiles apply across all networks rather than to each.
+func (b *Bot) share(primary *Bot) {
+	b.primary = primary
+	b.jobs = primary.jobs
+	b.playgroundSem = primary.playgroundSem
+	b.httpClient = primary.httpClient
+	b.metrics = primary.metrics
+	b.stats = primary.stats
+	b.cache = primary.cache
+	b.breaker = primary.breaker
+	b.paster = primary.paster
+}
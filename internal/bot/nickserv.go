@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+const (
+	rplLoggedIn = "900"
+
+	defaultNickServNick = "NickServ"
+	identifyTimeout     = 10 * time.Second
+)
+
+// shouldIdentify returns whether or not the bot should identify with NickServ, which is only done when SASL is not
+// in use.
+func (b *Bot) shouldIdentify() bool {
+	c := b.cfg()
+	return c.NickServPassword != "" && !c.useSASL()
+}
+
+// identify sends an IDENTIFY to NickServ, and returns a channel that is closed once the bot is identified.
+func (b *Bot) identify() <-chan struct{} {
+	c := b.cfg()
+	done := make(chan struct{})
+
+	b.identifyMu.Lock()
+	b.identified = done
+	b.identifyMu.Unlock()
+
+	params := []string{"IDENTIFY", c.NickServPassword}
+	if c.NickServUser != "" {
+		// Some services require the account name when it differs from the current nick
+		params = []string{"IDENTIFY", c.NickServUser, c.NickServPassword}
+	}
+
+	b.conn.Privmsg(c.NickServNick, strings.Join(params, " "))
+	return done
+}
+
+// markIdentified signals any pending identify that it has completed.
+func (b *Bot) markIdentified() {
+	b.identifyMu.Lock()
+	defer b.identifyMu.Unlock()
+	if b.identified != nil {
+		close(b.identified)
+		b.identified = nil
+	}
+}
+
+// identifyThenJoin identifies with NickServ, and joins channels once that completes or times out. This avoids
+// joining channels that require identification (+r) before the bot is identified.
+func (b *Bot) identifyThenJoin() {
+	select {
+	case <-b.identify():
+		infof("Identified with NickServ")
+	case <-time.After(identifyTimeout):
+		warnf("Timed out waiting to identify with NickServ, joining channels anyway")
+	}
+
+	b.joinChannels()
+}
+
+func (b *Bot) onLoggedIn(_ ircmsg.Message) {
+	b.markIdentified()
+}
+
+// onNickServNotice watches NickServ's notices for confirmation of identification, for services that do not send
+// RPL_LOGGEDIN.
+func (b *Bot) onNickServNotice(msg ircmsg.Message) {
+	nick, _, _ := ircevent.SplitNUH(msg.Prefix)
+	if len(msg.Params) < 2 || !strings.EqualFold(nick, b.cfg().NickServNick) {
+		return
+	}
+
+	text := strings.ToLower(msg.Params[1])
+	if strings.Contains(text, "you are now identified") || strings.Contains(text, "you are now recognized") {
+		b.markIdentified()
+	}
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errNoPaster = errors.New("no paste service configured")
+
+// Paster uploads text to a paste service, returning a link to it.
+type Paster interface {
+	Paste(content string) (string, error)
+}
+
+// formPaster uploads pastes as a file in a multipart form, and expects the link to the paste as the response body.
+// This matches the API of many simple paste services, eg `https://ix.io` with the field `f:1`, or `https://0x0.st`
+// with the field `file`.
+type formPaster struct {
+	url    string
+	field  string
+	client *http.Client
+}
+
+func newFormPaster(url, field string, transport http.RoundTripper) *formPaster {
+	return &formPaster{url: url, field: field, client: &http.Client{Transport: transport, Timeout: 10 * time.Second}}
+}
+
+// Paste implements Paster.
+func (p *formPaster) Paste(content string) (string, error) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	w, err := form.CreateFormFile(p.field, "output.txt")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write([]byte(content)); err != nil {
+		return "", err
+	}
+
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	res, err := p.client.Post(p.url, form.FormDataContentType(), body)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", fmt.Errorf("paste service returned %s", res.Status)
+	}
+
+	link := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(link, "http") {
+		return "", fmt.Errorf("unexpected response from paste service: %q", link)
+	}
+
+	return link, nil
+}
+
+// paste uploads content to the configured paste service.
+func (b *Bot) paste(content string) (string, error) {
+	if b.paster == nil {
+		return "", errNoPaster
+	}
+
+	link, err := b.paster.Paste(content)
+	if err != nil {
+		errorf("Unable to paste: %s", err)
+		return "", err
+	}
+
+	return link, nil
+}
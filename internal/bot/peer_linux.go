@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dialedByUs returns whether conn, accepted on a loopback listener, was dialed by this process. The other end of conn
+// is a socket whose local address is conn's remote address and the other way around; it is looked up in the kernel's
+// table of TCP sockets, and must be one of this process' open files.
+func dialedByUs(conn net.Conn) (bool, error) {
+	local, lok := conn.LocalAddr().(*net.TCPAddr)
+	remote, rok := conn.RemoteAddr().(*net.TCPAddr)
+	if !lok || !rok || !remote.IP.IsLoopback() {
+		return false, nil
+	}
+
+	inode, err := socketInode(remote.Port, local.Port)
+	if err != nil || inode == "" {
+		return false, err
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return false, err
+	}
+
+	want := "socket:[" + inode + "]"
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && target == want {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// socketInode returns the inode of the IPv4 loopback socket connected from localPort to remotePort, or an empty string
+// if there is none.
+func socketInode(localPort, remotePort int) (string, error) {
+	f, err := os.Open("/proc/self/net/tcp")
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...
+	lines := bufio.NewScanner(f)
+	lines.Scan()
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		if procPortIs(fields[1], localPort) && procPortIs(fields[2], remotePort) {
+			return fields[9], nil
+		}
+	}
+
+	return "", lines.Err()
+}
+
+// procPortIs returns whether addr, a loopback address as written in /proc/net/tcp (hex IP:hex port), has port. The IP
+// is written in host byte order, so both orders are accepted.
+func procPortIs(addr string, port int) bool {
+	i := strings.IndexByte(addr, ':')
+	if i < 0 {
+		return false
+	}
+
+	if ip := addr[:i]; ip != "0100007F" && ip != "7F000001" {
+		return false
+	}
+
+	p, err := strconv.ParseUint(addr[i+1:], 16, 16)
+	return err == nil && int(p) == port
+}
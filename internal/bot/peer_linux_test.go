@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestDialFromOtherProcess isn't a test, it is run by TestIRCProxyRefusesOtherProcesses in a separate process to dial
+// the proxy's listener. It exits successfully if the connection is closed without anything being proxied.
+func TestDialFromOtherProcess(t *testing.T) {
+	addr := os.Getenv("GOPLAY_IRC_TEST_DIAL")
+	if addr == "" {
+		t.Skip("only run by TestIRCProxyRefusesOtherProcesses")
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 8)); err != io.EOF {
+		t.Fatalf("read %d bytes, %v from the listener, want it closed", n, err)
+	}
+}
+
+func TestIRCProxyRefusesOtherProcesses(t *testing.T) {
+	p := newIRCProxy(fakeSOCKS5(t), "irc.example.com:6697", 5*time.Second)
+	addr, err := p.listen(0)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDialFromOtherProcess$")
+	cmd.Env = append(os.Environ(), "GOPLAY_IRC_TEST_DIAL="+addr)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("the other process was not refused: %v\n%s", err, out)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v, want the listener kept open for the bot", err)
+	}
+
+	defer conn.Close()
+	assertEcho(t, conn)
+}
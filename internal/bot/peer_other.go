@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package bot
+
+import "net"
+
+// dialedByUs returns whether conn, accepted on a loopback listener, was dialed by this process. There is no portable
+// way to tell, so any loopback connection is assumed to be ours; the listener is only open during a connection attempt.
+func dialedByUs(conn net.Conn) (bool, error) {
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	return ok && remote.IP.IsLoopback(), nil
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ergochat/irc-go/ircevent"
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+const (
+	rplISON       = "303"
+	rplMonOnline  = "730"
+	rplMonOffline = "731"
+
+	isonInterval = time.Minute
+)
+
+// adminNicks returns the nicks of all admins whose mask has a literal (non wildcard) nick.
+func (b *Bot) adminNicks() []string {
+	var out []string
+	for _, mask := range b.cfg().Admins {
+		nick := strings.SplitN(mask, "!", 2)[0]
+		if nick != "" && !strings.ContainsAny(nick, "*?") {
+			out = append(out, nick)
+		}
+	}
+
+	return out
+}
+
+func (b *Bot) monitorSupported() bool {
+	_, ok := b.conn.ISupport()["MONITOR"]
+	return ok
+}
+
+// startPresenceTracking begins tracking which admins are online. If the server supports MONITOR it is used, otherwise
+// isonLoop handles polling.
+func (b *Bot) startPresenceTracking() {
+	b.presenceMu.Lock()
+	b.adminsOnline = make(map[string]bool)
+	b.presenceMu.Unlock()
+
+	nicks := b.adminNicks()
+	if len(nicks) == 0 || !b.monitorSupported() {
+		return
+	}
+
+	b.conn.Send("MONITOR", "+", strings.Join(nicks, ","))
+}
+
+// isonLoop polls for admin presence using ISON, on servers that do not support MONITOR. It never returns.
+func (b *Bot) isonLoop() {
+	for range time.Tick(isonInterval) {
+		nicks := b.adminNicks()
+		if len(nicks) == 0 || !b.conn.Connected() || b.monitorSupported() {
+			continue
+		}
+
+		b.conn.Send("ISON", nicks...)
+	}
+}
+
+func (b *Bot) setAdminsOnline(nicks []string, online bool) {
+	b.presenceMu.Lock()
+	defer b.presenceMu.Unlock()
+	for _, n := range nicks {
+		nick, _, _ := ircevent.SplitNUH(n)
+		if nick != "" {
+			b.adminsOnline[strings.ToLower(nick)] = online
+		}
+	}
+}
+
+func (b *Bot) onMonOnline(msg ircmsg.Message) {
+	if len(msg.Params) < 2 {
+		return
+	}
+
+	b.setAdminsOnline(strings.Split(msg.Params[1], ","), true)
+}
+
+func (b *Bot) onMonOffline(msg ircmsg.Message) {
+	if len(msg.Params) < 2 {
+		return
+	}
+
+	b.setAdminsOnline(strings.Split(msg.Params[1], ","), false)
+}
+
+func (b *Bot) onISON(msg ircmsg.Message) {
+	if len(msg.Params) < 2 {
+		return
+	}
+
+	// ISON only lists the nicks that are online, anything we asked about that is missing is offline
+	b.setAdminsOnline(b.adminNicks(), false)
+	b.setAdminsOnline(strings.Fields(msg.Params[1]), true)
+}
+
+// onlineAdmins returns the nicks of all admins currently known to be online, sorted.
+func (b *Bot) onlineAdmins() []string {
+	b.presenceMu.Lock()
+	defer b.presenceMu.Unlock()
+
+	var out []string
+	for nick, online := range b.adminsOnline {
+		if online {
+			out = append(out, nick)
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+// AdminsOnlineCmd is the callback for the ~adminsonline IRC command, and responds with which admins are online.
+func (b *Bot) AdminsOnlineCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	online := b.onlineAdmins()
+	if len(online) == 0 {
+		reply("No admins are online")
+		return
+	}
+
+	reply("Admins online: %s", strings.Join(online, ", "))
+}
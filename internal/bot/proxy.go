@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// parseProxy parses and validates the configured proxy URL. Only socks5 proxies are supported, as they can carry
+// both the IRC connection and HTTP requests.
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "socks5" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q, only socks5 is supported", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("proxy URL has no host")
+	}
+
+	return u, nil
+}
+
+// ircProxy forwards the IRC connection to a server through a socks5 proxy. The irc library always dials the server
+// itself, so before each connection attempt a listener is opened on localhost for the library to dial instead. Each
+// listener only accepts connections made by this process, and is closed after the first, so that other local processes
+// can't use it to reach the proxy with our credentials. TLS is still negotiated end to end with the real server.
+type ircProxy struct {
+	proxy   *url.URL
+	server  string
+	timeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener // for the next connection attempt, until it is dialed
+}
+
+func newIRCProxy(u *url.URL, server string, timeout time.Duration) *ircProxy {
+	return &ircProxy{proxy: u, server: server, timeout: timeout}
+}
+
+// listen opens a listener for a connection attempt made within wait, closing any left unused by a previous attempt,
+// and returns the address the irc library should connect to.
+func (p *ircProxy) listen(wait time.Duration) (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("could not listen for proxied IRC connections: %w", err)
+	}
+
+	p.mu.Lock()
+	if p.listener != nil {
+		p.listener.Close()
+	}
+
+	p.listener = l
+	p.mu.Unlock()
+
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(wait + p.timeout))
+	go p.acceptOne(l)
+
+	return l.Addr().String(), nil
+}
+
+// acceptOne forwards the first connection made to l by this process, closing l as soon as it has been accepted.
+func (p *ircProxy) acceptOne(l net.Listener) {
+	local, err := acceptOwn(l)
+	l.Close()
+
+	p.mu.Lock()
+	if p.listener == l {
+		p.listener = nil
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		debugf("Proxy listener closed: %s", err)
+		return
+	}
+
+	p.forward(local)
+}
+
+// acceptOwn accepts connections on l until one is made by this process, refusing any made by another, so that nothing
+// else can take the irc library's place while the listener is open.
+func acceptOwn(l net.Listener) (net.Conn, error) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ours, err := dialedByUs(conn)
+		if ours {
+			return conn, nil
+		}
+
+		if err != nil {
+			warnf("Refusing proxy connection from %s, could not check where it came from: %s", conn.RemoteAddr(), err)
+		} else {
+			warnf("Refusing proxy connection from %s, which was not made by the bot", conn.RemoteAddr())
+		}
+
+		conn.Close()
+	}
+}
+
+func (p *ircProxy) forward(local net.Conn) {
+	defer local.Close()
+
+	remote, err := p.dial()
+	if err != nil {
+		errorf("Could not connect to %s through proxy %s: %s", p.server, p.proxy.Host, err)
+		return
+	}
+
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(remote, local); done <- struct{}{} }()
+	go func() { io.Copy(local, remote); done <- struct{}{} }()
+
+	// Either side closing ends the connection; the deferred closes unblock the other copy.
+	<-done
+}
+
+// dial connects to the server through the socks5 proxy, using the username and password in the proxy URL, if any.
+// The proxy resolves the server's name, so it is not leaked to the local resolver.
+func (p *ircProxy) dial() (net.Conn, error) {
+	var auth *proxy.Auth
+	if user := p.proxy.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{User: user.Username(), Password: password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", p.proxy.Host, auth, &net.Dialer{Timeout: p.timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout also bounds the handshake with the proxy, not just connecting to it
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	return dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", p.server)
+}
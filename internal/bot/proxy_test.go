@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeSOCKS5 starts a socks5 proxy without authentication that echoes back whatever is sent through it, and returns
+// its URL.
+func fakeSOCKS5(t *testing.T) *url.URL {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// Greeting, then a connect request for a domain: version, command, reserved, type, length
+				greeting := make([]byte, 3)
+				header := make([]byte, 5)
+				if _, err := io.ReadFull(conn, greeting); err != nil {
+					return
+				}
+
+				conn.Write([]byte{5, 0})
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+
+				if _, err := io.ReadFull(conn, make([]byte, int(header[4])+2)); err != nil {
+					return
+				}
+
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return &url.URL{Scheme: "socks5", Host: l.Addr().String()}
+}
+
+// assertEcho checks that what is written to conn comes back through the fake proxy.
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("PING x\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, 8)
+	if _, err := io.ReadFull(conn, got); err != nil || string(got) != "PING x\r\n" {
+		t.Fatalf("read %q, %v through the proxy, want the echo", got, err)
+	}
+}
+
+func TestIRCProxyAcceptsOneConnection(t *testing.T) {
+	p := newIRCProxy(fakeSOCKS5(t), "irc.example.com:6697", 5*time.Second)
+	addr, err := p.listen(0)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	defer conn.Close()
+	assertEcho(t, conn)
+
+	if other, err := net.Dial("tcp", addr); err == nil {
+		other.Close()
+		t.Error("a second connection to the listener succeeded, want it closed after the first")
+	}
+}
+
+func TestIRCProxyListenReplacesUnused(t *testing.T) {
+	p := newIRCProxy(fakeSOCKS5(t), "irc.example.com:6697", 5*time.Second)
+	first, err := p.listen(0)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	second, err := p.listen(0)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	if conn, err := net.Dial("tcp", first); err == nil {
+		conn.Close()
+		t.Error("the unused listener from the first attempt is still open")
+	}
+
+	conn, err := net.Dial("tcp", second)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	defer conn.Close()
+	assertEcho(t, conn)
+}
+
+func TestIRCProxyListenerExpires(t *testing.T) {
+	p := newIRCProxy(fakeSOCKS5(t), "irc.example.com:6697", 10*time.Millisecond)
+	addr, err := p.listen(0)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Error("the listener is still open after the connection attempt should have been made")
+	}
+}
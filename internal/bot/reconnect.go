@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// connectWithBackoff makes the initial connection to the server, retrying with backoff on failure. Once connected,
+// reconnection is handled by the irc library's Loop, with connLogWriter adjusting the delay between attempts.
+func (b *Bot) connectWithBackoff() error {
+	for {
+		b.armProxy(0)
+		err := b.irc.Connect()
+		if err == nil {
+			b.resetBackoff()
+			return nil
+		}
+
+		if b.isStopping() {
+			return err
+		}
+
+		errorf("Unable to connect: %s", err)
+		delay, err := b.connectFailed()
+		if err != nil {
+			return err
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// resetBackoff resets the backoff, after connecting successfully or losing an established connection.
+func (b *Bot) resetBackoff() {
+	b.connectFailures = 0
+	b.irc.ReconnectFreq = b.cfg().ReconnectDelay
+}
+
+// connectFailed records a failed connection attempt, and returns how long to wait before the next one. After
+// ReconnectMaxFailures consecutive failures it returns an error instead, and the bot should stop trying. Only this
+// bot gives up, so other networks in a Group are left connected.
+func (b *Bot) connectFailed() (time.Duration, error) {
+	c := b.cfg()
+	b.connectFailures++
+	if c.ReconnectMaxFailures > 0 && b.connectFailures >= c.ReconnectMaxFailures {
+		return 0, fmt.Errorf("giving up on %s after %d consecutive failures to connect", c.Server, b.connectFailures)
+	}
+
+	delay := c.ReconnectDelay
+	for i := 1; i < b.connectFailures && delay < c.ReconnectMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > c.ReconnectMaxDelay {
+		delay = c.ReconnectMaxDelay
+	}
+
+	warnf("Reconnecting in %s (attempt %d)", delay, b.connectFailures+1)
+	b.irc.ReconnectFreq = delay
+	return delay, nil
+}
+
+// armProxy opens the proxy's listener for the next connection attempt, which Loop makes within wait, and points the
+// irc library at it. It does nothing if no proxy is configured.
+func (b *Bot) armProxy(wait time.Duration) {
+	if b.ircProxy == nil {
+		return
+	}
+
+	addr, err := b.ircProxy.listen(wait)
+	if err != nil {
+		errorf("%s", err)
+		return
+	}
+
+	b.irc.Server = addr
+}
+
+func (b *Bot) isStopping() bool {
+	b.inFlightMu.Lock()
+	defer b.inFlightMu.Unlock()
+	return b.stopping
+}
+
+var (
+	reconnectFailedLog = []byte("Error while reconnecting")
+	disconnectedLog    = []byte("Error, disconnected")
+)
+
+// connLogWriter passes the irc library's logs through to our logger, watching for the messages Loop logs on
+// disconnect and on failing to reconnect, which TestReconnectGivesUp checks it still logs. The library offers no
+// other way to observe these, and Loop reads ReconnectFreq before each attempt, so updating it here is enough to
+// implement backoff. SASL and the connect callbacks are run by the library on every connection.
+type connLogWriter struct {
+	b *Bot
+}
+
+func (w *connLogWriter) Write(p []byte) (int, error) {
+	// Both messages are logged from Loop's goroutine, between connection attempts
+	level := LevelDebug
+	switch {
+	case bytes.Contains(p, disconnectedLog):
+		level = LevelWarn
+		w.b.resetBackoff()
+		w.b.armProxy(w.b.irc.ReconnectFreq)
+	case bytes.Contains(p, reconnectFailedLog):
+		level = LevelError
+		if _, err := w.b.connectFailed(); err != nil {
+			// Loop returns instead of making another attempt once the connection has quit
+			w.b.gaveUp = err
+			w.b.irc.Quit()
+			break
+		}
+
+		w.b.armProxy(w.b.irc.ReconnectFreq)
+	}
+
+	output(1, level, "irc: "+string(bytes.TrimSpace(p)))
+	return len(p), nil
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that can be written to from several goroutines, for capturing logs.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *lockedBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// captureLogs sends the standard logger's output to a buffer for the duration of the test.
+func captureLogs(t *testing.T) *lockedBuffer {
+	t.Helper()
+
+	buf := &lockedBuffer{}
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return buf
+}
+
+// fakeIRCServer accepts a single connection, registers the client, then disconnects it. Nothing is listening
+// afterwards, so every reconnection attempt fails. It returns the address to connect to.
+func fakeIRCServer(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		lines := bufio.NewScanner(conn)
+		for lines.Scan() {
+			switch fields := strings.Fields(lines.Text()); fields[0] {
+			case "CAP":
+				if len(fields) > 1 && fields[1] == "LS" {
+					fmt.Fprint(conn, ":irc.example.com CAP * LS :\r\n")
+				}
+			case "USER":
+				// Registration ends at the MOTD, and the client has seen it once it answers the PING sent after it
+				fmt.Fprint(conn, ":irc.example.com 001 goplay :Welcome\r\n:irc.example.com 422 goplay :No MOTD\r\n")
+				fmt.Fprint(conn, "PING :registered\r\n")
+			case "PONG":
+				return
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// TestReconnectGivesUp runs a bot against a server that drops it and then goes away. Backoff depends on recognising
+// the messages the irc library logs on disconnecting and on failing to reconnect, so this also checks that the
+// library still logs them as connLogWriter expects.
+func TestReconnectGivesUp(t *testing.T) {
+	logs := captureLogs(t)
+	b := newTestBot(t, func(c *BotConfig) {
+		c.Server = fakeIRCServer(t)
+		c.UseTLS = false
+		c.ReconnectDelay = 10 * time.Millisecond
+		c.ReconnectMaxDelay = 10 * time.Millisecond
+		c.ReconnectMaxFailures = 2
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- b.Run() }()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "2 consecutive failures") {
+			t.Errorf("Run() error = %v, want giving up after 2 failures", err)
+		}
+	case <-time.After(10 * time.Second):
+		b.Stop("")
+		t.Fatalf("Run() did not give up reconnecting, logs:\n%s", logs)
+	}
+
+	for _, want := range []string{"WARN irc: " + string(disconnectedLog), "ERROR irc: " + string(reconnectFailedLog)} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs do not contain %q, the irc library's messages may have changed:\n%s", want, logs)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+)
+
+// RedoCmd is the callback for the ~redo IRC command. It re-runs the last code the user passed to ~eval, optionally
+// with additional code appended, or a substitution applied.
+func (b *Bot) RedoCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	b.lastEvalsMu.Lock()
+	last, ok := b.lastEvals[ctx.UserKey]
+	b.lastEvalsMu.Unlock()
+
+	if !ok {
+		reply("You have no previous eval to redo")
+		return
+	}
+
+	code, err := applyRedo(last, strings.TrimSpace(args))
+	if err != nil {
+		reply("Unable to redo: %s", err)
+		return
+	}
+
+	b.EvalCmd(ctx, code, reply)
+}
+
+// applyRedo modifies code as requested by a ~redo invocation. A sed style substitution (s/old/new/, with an optional
+// g flag to replace all occurrences) is applied literally, anything else is appended as a new statement.
+func applyRedo(code, modification string) (string, error) {
+	if modification == "" {
+		return code, nil
+	}
+
+	if !strings.HasPrefix(modification, "s") || len(modification) < 2 || isCodeChar(modification[1]) {
+		return code + "\n" + modification, nil
+	}
+
+	delim := string(modification[1])
+	parts := strings.Split(modification[2:], delim)
+	if len(parts) != 3 {
+		return "", errors.New("substitutions must be of the form s/old/new/")
+	}
+
+	old, replacement, flags := parts[0], parts[1], parts[2]
+	if old == "" {
+		return "", errors.New("cannot substitute an empty string")
+	}
+
+	if !strings.Contains(code, old) {
+		return "", errors.New("nothing to substitute")
+	}
+
+	switch flags {
+	case "":
+		return strings.Replace(code, old, replacement, 1), nil
+	case "g":
+		return strings.ReplaceAll(code, old, replacement), nil
+	default:
+		return "", errors.New("the only supported substitution flag is g")
+	}
+}
+
+// isCodeChar returns whether or not c could follow an `s` at the start of a go statement, and thus shouldn't be
+// treated as a substitution delimiter.
+func isCodeChar(c byte) bool {
+	return c == '_' || c == ' ' || c == '.' || c == '(' || c == '[' || c == '=' || c == ':' || c == ',' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
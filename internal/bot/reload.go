@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// cfg returns the current config. The returned config must not be modified, Reload replaces it wholesale.
+func (b *Bot) cfg() *BotConfig {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return b.config
+}
+
+// Reload re-reads the config at path, and applies any changes that can be made without reconnecting: everything
+// ~reload applies, and channels. Changes to anything that requires a reconnect are logged and ignored.
+func (b *Bot) Reload(path string) error {
+	loaded, err := b.loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	loaded.setDefaults()
+	if err := validateJoinChannels(loaded.JoinChannels); err != nil {
+		return err
+	}
+
+	if err := validateIgnores(loaded.Ignores); err != nil {
+		return err
+	}
+
+	old := b.cfg()
+	restartRequired := []struct {
+		name     string
+		was, now interface{}
+	}{
+		{"server", old.Server, loaded.Server},
+		{"nick", old.Nick, loaded.Nick},
+		{"user", old.User, loaded.User},
+		{"real_name", old.RealName, loaded.RealName},
+		{"sasl_user", old.SASLUser, loaded.SASLUser},
+		{"sasl_password", old.SASLPassword, loaded.SASLPassword},
+		{"use_tls", old.UseTLS, loaded.UseTLS},
+		{"ca_cert_file", old.CACertFile, loaded.CACertFile},
+		{"tls_cert_file", old.TLSCertFile, loaded.TLSCertFile},
+		{"tls_key_file", old.TLSKeyFile, loaded.TLSKeyFile},
+		{"tls_server_name", old.TLSServerName, loaded.TLSServerName},
+		{"tls_insecure_skip_verify", old.TLSInsecureSkipVerify, loaded.TLSInsecureSkipVerify},
+		{"proxy", old.Proxy, loaded.Proxy},
+	}
+
+	for _, f := range restartRequired {
+		if f.was != f.now {
+			warnf("Config change to %s requires restart, ignoring", f.name)
+		}
+	}
+
+	updated := b.applyAccess(loaded, true)
+
+	infof("Config reloaded")
+	if b.conn.Connected() {
+		// Otherwise channels are joined from the new config on connect
+		b.updateChannels(old.JoinChannels, updated.JoinChannels)
+	}
+
+	return nil
+}
+
+// ReloadCmd is the callback for the ~reload IRC command. Unlike a full Reload, it only re-reads the parts of the config
+// that control who can do what: admins, admin accounts, ignores, allowed channels, and command prefixes. These are all
+// applied, or none are if any is invalid.
+func (b *Bot) ReloadCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	changed, err := b.reloadAccess()
+	if err != nil {
+		reply("Unable to reload: %s", err)
+		return
+	}
+
+	if len(changed) == 0 {
+		reply("Reloaded, nothing changed")
+		return
+	}
+
+	reply("Reloaded, updated %s", strings.Join(changed, ", "))
+}
+
+// reloadAccess applies the admins, ignores, allowed channels, and command prefixes from the config file, returning
+// the names of those that changed.
+func (b *Bot) reloadAccess() ([]string, error) {
+	path := b.cfg().path
+	if path == "" {
+		return nil, errors.New("config was not loaded from a file")
+	}
+
+	loaded, err := b.loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateIgnores(loaded.Ignores); err != nil {
+		return nil, err
+	}
+
+	old := b.cfg()
+	updated := b.applyAccess(loaded, false)
+	fields := []struct {
+		name     string
+		was, now interface{}
+	}{
+		{"admins", old.Admins, updated.Admins},
+		{"admin_accounts", old.AdminAccounts, updated.AdminAccounts},
+		{"ignores", old.Ignores, updated.Ignores},
+		{"allowed_channels", old.AllowedChannels, updated.AllowedChannels},
+		{"command_prefix", old.CommandPrefix, updated.CommandPrefix},
+		{"channel_prefixes", old.ChannelPrefixes, updated.ChannelPrefixes},
+		{"prefer_prefix_trigger", old.PreferPrefixTrigger, updated.PreferPrefixTrigger},
+	}
+
+	var changed []string
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.was, f.now) {
+			changed = append(changed, f.name)
+		}
+	}
+
+	infof("Access config reloaded, changed: %v", changed)
+	return changed, nil
+}
+
+// validateIgnores returns an error if any of masks is not a valid ignore mask.
+func validateIgnores(masks []string) error {
+	for _, mask := range masks {
+		if _, err := compileMask(mask); err != nil {
+			return fmt.Errorf("invalid ignore: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyAccess replaces the admins, ignores, allowed channels, and command prefixes in the current config with those
+// in loaded, along with JoinChannels if withChannels is set, and returns the new config. Both Reload and ~reload go
+// through it, so that they apply these the same way. loaded must already have been validated.
+func (b *Bot) applyAccess(loaded *BotConfig, withChannels bool) *BotConfig {
+	b.configMu.Lock()
+	old := b.config
+	updated := *old
+	updated.Admins = loaded.Admins
+	updated.AdminAccounts = loaded.AdminAccounts
+	updated.Ignores = loaded.Ignores
+	updated.AllowedChannels = loaded.AllowedChannels
+	updated.CommandPrefix = loaded.CommandPrefix
+	updated.ChannelPrefixes = loaded.ChannelPrefixes
+	updated.PreferPrefixTrigger = loaded.PreferPrefixTrigger
+	if withChannels {
+		updated.JoinChannels = loaded.JoinChannels
+	}
+
+	b.config = &updated
+	b.adminMasks = compileMasks(updated.Admins)
+	b.configMu.Unlock()
+
+	b.updateIgnores(old.Ignores, updated.Ignores)
+
+	// Admin tracking is set up from the new config on connect
+	if !reflect.DeepEqual(old.Admins, updated.Admins) && b.conn.Connected() {
+		if b.monitorSupported() {
+			b.conn.Send("MONITOR", "C")
+		}
+
+		b.startPresenceTracking()
+	}
+
+	return &updated
+}
+
+// updateChannels joins any channels in current that were not in previous, and parts any in previous that are no
+// longer in current. Both are JoinChannels entries, which may include keys. Channels joined at runtime by an admin are
+// left alone.
+func (b *Bot) updateChannels(previous, current []string) {
+	previousNames, currentNames := channelNames(previous), channelNames(current)
+	for _, entry := range current {
+		ch, key := splitChannelKey(entry)
+		if !containsFold(previousNames, ch) && !b.inChannel(ch) {
+			if err := b.join(ch, key); err != nil {
+				errorf("Unable to join %s: %s", ch, err)
+			}
+		}
+	}
+
+	for _, ch := range previousNames {
+		if containsFold(currentNames, ch) || !b.inChannel(ch) {
+			continue
+		}
+
+		b.channelsMu.Lock()
+		_, extra := b.extraChannels[strings.ToLower(ch)]
+		b.channelsMu.Unlock()
+
+		if !extra {
+			b.part(ch, "Removed from config")
+		}
+	}
+}
+
+// containsFold returns whether or not list contains s, case insensitively.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReloadAppliesAccess(t *testing.T) {
+	const config = `
+command_prefix = "!"
+admins = ["me!*@my/cloak"]
+ignores = ["spammer!*@*"]
+allowed_channels = ["#go"]
+`
+
+	reloads := []struct {
+		name   string
+		reload func(b *Bot, path string) error
+	}{
+		{name: "SIGHUP", reload: (*Bot).Reload},
+		{name: "~reload", reload: func(b *Bot, _ string) error {
+			_, err := b.reloadAccess()
+			return err
+		}},
+	}
+
+	for _, r := range reloads {
+		t.Run(r.name, func(t *testing.T) {
+			path := writeConfig(t, config)
+			b := newTestBot(t, func(c *BotConfig) { c.path = path })
+			useFakeConn(b)
+
+			if err := r.reload(b, path); err != nil {
+				t.Fatalf("reload error = %v", err)
+			}
+
+			c := b.cfg()
+			if c.CommandPrefix != "!" {
+				t.Errorf("command_prefix = %q, want %q", c.CommandPrefix, "!")
+			}
+
+			if want := []string{"me!*@my/cloak"}; !reflect.DeepEqual(c.Admins, want) {
+				t.Errorf("admins = %q, want %q", c.Admins, want)
+			}
+
+			if !b.matchesAdminMask("me!user@my/cloak") {
+				t.Error("admin masks were not recompiled")
+			}
+
+			if want := []string{"#go"}; !reflect.DeepEqual(c.AllowedChannels, want) {
+				t.Errorf("allowed_channels = %q, want %q", c.AllowedChannels, want)
+			}
+
+			if !b.isIgnored("spammer!user@host") {
+				t.Error("ignores were not applied")
+			}
+		})
+	}
+}
+
+func TestReloadRejectsInvalidIgnores(t *testing.T) {
+	path := writeConfig(t, `command_prefix = "!"`+"\n"+`ignores = ["notamask"]`)
+	b := newTestBot(t, func(c *BotConfig) { c.path = path })
+	useFakeConn(b)
+
+	if err := b.Reload(path); err == nil {
+		t.Error("Reload() with an invalid ignore succeeded, want error")
+	}
+
+	if b.cfg().CommandPrefix != "~" {
+		t.Errorf("command_prefix = %q after a failed reload, want it unchanged", b.cfg().CommandPrefix)
+	}
+}
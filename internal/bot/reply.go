@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// The longest line the server will accept, including the trailing CRLF
+	defaultMaxLineLen = 512
+	// The longest hostname the server is likely to show in our prefix. As the real one is unknown (and may be a
+	// cloak), room is left for the worst case
+	maxHostLen = 63
+
+	truncatedMarker = " [truncated]"
+)
+
+// maxMessageLen returns the maximum number of bytes of text that can be sent in a single PRIVMSG to target, once the
+// server has prefixed it with our nick!user@host. Exceeding this would have the server cut the message short,
+// possibly in the middle of a multibyte character.
+func (b *Bot) maxMessageLen(target string) int {
+	lineLen := b.irc.MaxLineLen
+	if lineLen == 0 {
+		lineLen = defaultMaxLineLen
+	}
+
+	nick, user := b.conn.CurrentNick(), b.cfg().User
+	if user == "" {
+		user = nick
+	}
+
+	// :nick!~user@host PRIVMSG target :text\r\n
+	overhead := len(":!~@ PRIVMSG  :\r\n") + len(nick) + len(user) + maxHostLen + len(target)
+	return lineLen - overhead
+}
+
+// SplitReply sends text to target, splitting it across multiple messages if it is too long for a single line or
+// contains newlines. Continuation messages are numbered, and at most MaxReplyLines messages are sent. Messages are
+// paced according to MessagesPerSecond, so this may block.
+func (b *Bot) SplitReply(target, text string) error {
+	return b.sendSplit(target, text, b.cfg().MaxReplyLines)
+}
+
+// sendSplit is SplitReply, sending at most maxParts messages.
+func (b *Bot) sendSplit(target, text string, maxParts int) error {
+	for _, part := range splitMessage(text, b.maxMessageLen(target), maxParts) {
+		b.throttle.wait()
+		if err := b.conn.Privmsg(target, part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newReplyFunc returns the ReplyFunc for a command invoked by nick, that replies to target. Replies without arguments
+// are sent as is, rather than used as a format string, so that they can safely contain program output.
+func (b *Bot) newReplyFunc(target, nick string, inChannel bool) ReplyFunc {
+	return func(s string, a ...interface{}) error {
+		if len(a) != 0 {
+			s = fmt.Sprintf(s, a...)
+		}
+
+		return b.SplitReply(target, attributeReply(nick, s, inChannel))
+	}
+}
+
+// attributeReply prefixes text with nick if the reply is in a channel, so that it's clear who it's for when several
+// commands are running at once. Private replies can only be for one person, so are never prefixed.
+func attributeReply(nick, text string, inChannel bool) string {
+	if !inChannel {
+		return text
+	}
+
+	return fmt.Sprintf("(%s) %s", nick, text)
+}
+
+// wouldTruncate returns whether or not SplitReply would have to truncate text, once attributed by a ReplyFunc, to fit
+// it within MaxReplyLines.
+func (b *Bot) wouldTruncate(ctx *CommandContext, text string) bool {
+	text = attributeReply(ctx.Nick, text, ctx.Channel != "")
+	lines := splitMessage(text, b.maxMessageLen(ctx.Target), b.cfg().MaxReplyLines)
+	return len(lines) > 0 && strings.HasSuffix(lines[len(lines)-1], truncatedMarker)
+}
+
+// splitMessage splits text into lines no longer than maxLen bytes, preferring to split on newlines, then spaces,
+// and never splitting within a rune. If more than one line is needed, each is prefixed with a continuation marker
+// ("1/3 "). If more than maxParts lines would be needed, the excess is dropped and the last line is marked as
+// truncated.
+func splitMessage(text string, maxLen, maxParts int) []string {
+	if len(text) <= maxLen && !strings.Contains(text, "\n") {
+		return []string{text}
+	}
+
+	// Leave room for both the continuation marker and the truncation marker
+	markerLen := len(fmt.Sprintf("%d/%d ", maxParts, maxParts))
+	lineLen := maxLen - markerLen - len(truncatedMarker)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		for len(line) > lineLen {
+			cut := splitPoint(line, lineLen)
+			lines = append(lines, strings.TrimRight(line[:cut], " "))
+			line = strings.TrimLeft(line[cut:], " ")
+		}
+
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) <= 1 {
+		return lines
+	}
+
+	truncated := false
+	if len(lines) > maxParts {
+		lines = lines[:maxParts]
+		truncated = true
+	}
+
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%d/%d %s", i+1, len(lines), line)
+	}
+
+	if truncated {
+		lines[len(lines)-1] += truncatedMarker
+	}
+
+	return lines
+}
+
+// splitPoint finds the index at which to split s such that s[:idx] is at most maxLen bytes. A space is preferred if
+// one exists in the latter half of the allowed length, otherwise the split is made on a rune boundary.
+func splitPoint(s string, maxLen int) int {
+	if idx := strings.LastIndexByte(s[:maxLen+1], ' '); idx > maxLen/2 {
+		return idx
+	}
+
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return cut
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	long50 := strings.Repeat("a", 50)
+	tests := []struct {
+		name     string
+		text     string
+		maxLen   int
+		maxParts int
+		want     []string
+	}{
+		{name: "fits", text: "hello world", maxLen: 50, maxParts: 3, want: []string{"hello world"}},
+		{name: "exactly fits", text: long50, maxLen: 50, maxParts: 3, want: []string{long50}},
+		{name: "newlines", text: "one\ntwo", maxLen: 50, maxParts: 3, want: []string{"1/2 one", "2/2 two"}},
+		{name: "blank lines", text: "one\n\n\ntwo\n", maxLen: 50, maxParts: 3, want: []string{"1/2 one", "2/2 two"}},
+		{name: "single line after trimming", text: "one\n", maxLen: 50, maxParts: 3, want: []string{"one"}},
+		{
+			name: "truncated", text: "1\n2\n3\n4", maxLen: 50, maxParts: 2,
+			want: []string{"1/2 1", "2/2 2" + truncatedMarker},
+		},
+		{
+			// 50 - len("3/3 ") - len(" [truncated]") leaves 34 bytes per line, and a space in the latter half
+			name: "split on space", text: strings.Repeat("a", 30) + " " + strings.Repeat("b", 30),
+			maxLen: 50, maxParts: 3,
+			want: []string{"1/2 " + strings.Repeat("a", 30), "2/2 " + strings.Repeat("b", 30)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.text, tt.maxLen, tt.maxParts)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("splitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageRuneBoundaries(t *testing.T) {
+	// Each rune is several bytes, so most byte limits fall within one
+	for _, r := range []string{"é", "€", "🙂"} {
+		for maxLen := 40; maxLen < 48; maxLen++ {
+			text := strings.Repeat(r, 100)
+			lines := splitMessage(text, maxLen, 100)
+
+			var joined strings.Builder
+			for i, line := range lines {
+				if len(line) > maxLen {
+					t.Errorf("%q at %d: line %d is %d bytes", r, maxLen, i, len(line))
+				}
+
+				if !utf8.ValidString(line) {
+					t.Errorf("%q at %d: line %d is not valid UTF-8: %q", r, maxLen, i, line)
+				}
+
+				joined.WriteString(line[strings.IndexByte(line, ' ')+1:])
+			}
+
+			if joined.String() != text {
+				t.Errorf("%q at %d: lines %q do not add up to the input", r, maxLen, lines)
+			}
+		}
+	}
+}
+
+func TestSplitPoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		maxLen int
+		want   int
+	}{
+		{name: "space in latter half", s: "aaaaaa bbbbbb", maxLen: 8, want: 6},
+		{name: "space too early", s: "a bbbbbbbbbbb", maxLen: 8, want: 8},
+		{name: "space at limit", s: "aaaaaaaa bbbb", maxLen: 8, want: 8},
+		{name: "within a rune", s: "aaaaaaa€bbb", maxLen: 8, want: 7},
+		{name: "at a rune start", s: "aaaaaaaa€bb", maxLen: 8, want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitPoint(tt.s, tt.maxLen); got != tt.want {
+				t.Errorf("splitPoint(%q, %d) = %d, want %d", tt.s, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttributeReply(t *testing.T) {
+	if got := attributeReply("user", "hi", true); got != "(user) hi" {
+		t.Errorf("attributeReply() in channel = %q, want %q", got, "(user) hi")
+	}
+
+	if got := attributeReply("user", "hi", false); got != "hi" {
+		t.Errorf("attributeReply() in private = %q, want %q", got, "hi")
+	}
+}
+
+func TestReplyFuncAttribution(t *testing.T) {
+	b := newTestBot(t, nil)
+	f := useFakeConn(b)
+	plain := func(s string) func(ReplyFunc) { return func(r ReplyFunc) { r(s) } }
+	formatted := func(s, arg string) func(ReplyFunc) { return func(r ReplyFunc) { r(s, arg) } }
+
+	tests := []struct {
+		name      string
+		inChannel bool
+		send      func(reply ReplyFunc)
+		want      string
+	}{
+		{name: "channel, plain", inChannel: true, send: plain("No errors"), want: "(user) No errors"},
+		{name: "channel, formatted", inChannel: true, send: formatted("Errors: %s", "x"), want: "(user) Errors: x"},
+		{name: "private, plain", send: plain("No errors"), want: "No errors"},
+		{name: "private, formatted", send: formatted("Errors: %s", "x"), want: "Errors: x"},
+		{name: "plain is not a format string", inChannel: true, send: plain("100%d"), want: "(user) 100%d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "user"
+			if tt.inChannel {
+				target = "#go"
+			}
+
+			tt.send(b.newReplyFunc(target, "user", tt.inChannel))
+			assertPrivmsgs(t, f, fakePrivmsg{target, tt.want})
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Levels for sanitizeOutput
+const (
+	// SanitizeSuppress suppresses output entirely if it contains any non-printable characters, including IRC
+	// formatting codes.
+	SanitizeSuppress = "suppress"
+	// SanitizeStrip removes IRC formatting codes (bold, colours, reset, etc) from output, and suppresses it only if
+	// other non-printable characters remain.
+	SanitizeStrip = "strip"
+)
+
+const suppressedOutput = "Output suppressed, non-printable characters detected."
+
+// ircFormattingRe matches IRC formatting codes, including the arguments to colour codes.
+var ircFormattingRe = regexp.MustCompile(
+	"\x03(?:\\d{1,2}(?:,\\d{1,2})?)?" + // colour
+		"|\x04(?:[0-9a-fA-F]{6}(?:,[0-9a-fA-F]{6})?)?" + // hex colour
+		"|[\x02\x0f\x11\x16\x1d\x1e\x1f]", // bold, reset, monospace, reverse, italic, strikethrough, underline
+)
+
+// sanitizeOutput prepares a single line of program output to be sent to IRC, according to level.
+func sanitizeOutput(s, level string) string {
+	s = strings.TrimSpace(s)
+	if level == SanitizeStrip {
+		s = ircFormattingRe.ReplaceAllString(s, "")
+	}
+
+	for _, c := range s {
+		if !unicode.IsPrint(c) {
+			return suppressedOutput
+		}
+	}
+
+	return s
+}
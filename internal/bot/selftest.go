@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"context"
+	"strings"
+)
+
+// selfTestCode is the program run by the startup self test. It does nothing, so can only fail if the playground can't
+// be used at all.
+const selfTestCode = "package main\n\nfunc main() {}\n"
+
+// selfTest runs selfTestCode on the playground, and logs whether or not it worked, so that problems reaching the
+// playground show up at startup rather than on the first command. It is run once, after the first connection, when
+// StartupSelfTest is set.
+func (b *Bot) selfTest() {
+	backend := b.cfg().PlaygroundBackend
+	res, _, err := b.runCode(context.Background(), selfTestCode, nil, backend, false, false, false)
+	switch {
+	case err != nil:
+		errorf("Startup self test FAILED, the playground is unreachable: %s", err)
+	case len(res.Errors) != 0:
+		errorf("Startup self test FAILED, the playground could not compile an empty program: %s",
+			strings.TrimSpace(res.Errors))
+	case res.Status != 0:
+		errorf("Startup self test FAILED, an empty program exited with status %d", res.Status)
+	default:
+		infof("Startup self test passed, the playground is reachable (took %s)", formatElapsed(res.Elapsed))
+	}
+}
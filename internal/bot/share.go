@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// snippetIDPattern matches a playground snippet ID: the start of a URL-safe base64 hash, 11 characters long, or 10 for
+// older snippets.
+const snippetIDPattern = `[a-zA-Z0-9_-]{10,11}`
+
+// bareSnippetIDRe matches arguments that are entirely a playground snippet ID. It is anchored so that neither code
+// passed to ~share nor other URLs can be mistaken for an ID. Short words can still look like one, so IDs from users
+// are checked to exist before use.
+var bareSnippetIDRe = regexp.MustCompile(`^` + snippetIDPattern + `(?:\.go)?$`)
+
+// maxShareBytes is the largest snippet the playground will share.
+const maxShareBytes = 64 << 10
+
+// errShareTooLarge is returned by shareCode for code too large for the playground to share. Unlike other share
+// failures, retrying won't help.
+var errShareTooLarge = errors.New("snippet too large to share")
+
+// shareCode uploads code to the playground, and returns a link to it. The /share endpoint is called directly, as
+// goplay's Share ignores the response status, turning errors into broken links.
+func (b *Bot) shareCode(ctx context.Context, code []byte) (string, error) {
+	if len(code) > maxShareBytes {
+		return "", errShareTooLarge
+	}
+
+	var link string
+	err := b.callPlayground(ctx, func() error {
+		var err error
+		link, err = b.postShare(ctx, code)
+		return err
+	})
+
+	return link, err
+}
+
+// postShare sends code to the playground's /share endpoint, returning a link to the snippet.
+func (b *Bot) postShare(ctx context.Context, code []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, defaultPlayground+"/share", bytes.NewReader(code))
+	if err != nil {
+		return "", err
+	}
+
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error from playground: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusRequestEntityTooLarge:
+		return "", errShareTooLarge
+	case res.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("unexpected response from playground: %s", res.Status)
+	}
+
+	// The response is just the snippet's ID
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64))
+	if err != nil {
+		return "", err
+	}
+
+	id := strings.TrimSpace(string(body))
+	if !bareSnippetIDRe.MatchString(id) {
+		return "", fmt.Errorf("unexpected snippet ID from playground: %q", id)
+	}
+
+	return defaultPlayground + "/p/" + id, nil
+}
+
+// ShareCmd is the callback for the ~share IRC command. Code is formatted, with imports resolved, and shared without
+// being run. Playground links are already shared, so are checked to exist and responded to with their canonical link,
+// while gists and raw links are downloaded and shared as is.
+func (b *Bot) ShareCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		reply("Cannot share empty code")
+		return
+	}
+
+	shareCtx, cancel := context.WithTimeout(context.Background(), b.cfg().CompileTimeout)
+	defer cancel()
+
+	var code []byte
+	src, err := parseSnippetSource(args)
+	switch {
+	case err == nil && !src.external:
+		// Only its existence matters, so a snippet too large to run is still fine
+		var tooLarge *sourceTooLargeError
+		_, err := b.downloadSnippet(shareCtx, args)
+		switch {
+		case errors.Is(err, errSnippetNotFound):
+			reply("Snippet does not exist")
+		case err != nil && !errors.As(err, &tooLarge):
+			warnf("Unable to get snippet: %s", err)
+			ctx.Fail(err)
+			reply("Unable to get snippet: %s", err)
+		default:
+			reply("%s/p/%s", defaultPlayground, src.id)
+		}
+
+		return
+
+	case err == nil && src.external:
+		downloaded, err := b.downloadSnippet(shareCtx, args)
+		if err != nil {
+			warnf("Unable to get snippet: %s", err)
+			ctx.Fail(err)
+			reply("Unable to get snippet: %s", err)
+			return
+		}
+
+		code = []byte(downloaded)
+
+	default:
+		if err := b.checkSourceSize(args); err != nil {
+			reply("%s", err)
+			return
+		}
+
+		wrapped, err := wrapEvalCode(b.evalTemplate, args, 0, false)
+		if err != nil {
+			reply("%s", err)
+			return
+		}
+
+		if code, err = b.format(shareCtx, []byte(wrapped), true); err != nil {
+			reply("Unable to format code: %s", explainOrTrim(err.Error()))
+			return
+		}
+	}
+
+	link, err := b.shareCode(shareCtx, code)
+	if err != nil {
+		errorf("Unable to create share link: %s", err)
+		ctx.Fail(err)
+		reply("Unable to create share link: %s", explainOrTrim(err.Error()))
+		return
+	}
+
+	reply("%s", link)
+}
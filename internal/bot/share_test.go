@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc fakes the bot's HTTP requests.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestShareCmdChecksSnippetExists(t *testing.T) {
+	b, f := newDispatchBot(t, func(c *BotConfig) { c.CommandCooldown = -1 })
+	b.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := http.StatusNotFound
+		if req.URL.Path == "/p/abcdefgh12.go" {
+			status = http.StatusOK
+		}
+
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("package main"))}, nil
+	})}
+
+	b.startWorkers()
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~share abcdefgh12"))
+	b.inFlight.Wait()
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) " + defaultPlayground + "/p/abcdefgh12"})
+
+	b.onPrivmsg(privmsgFrom("user!u@host", "#go", "~share helloworld"))
+	b.inFlight.Wait()
+	assertPrivmsgs(t, f, fakePrivmsg{"#go", "(user) Snippet does not exist"})
+}
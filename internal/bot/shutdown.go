@@ -0,0 +1,42 @@
+package bot
+
+import "time"
+
+// startInFlight records that a queued command is starting, and returns false if the bot is stopping and the command
+// should not be run. If it returns true, inFlight.Done must be called once the command completes.
+func (b *Bot) startInFlight() bool {
+	b.inFlightMu.Lock()
+	defer b.inFlightMu.Unlock()
+	if b.stopping {
+		return false
+	}
+
+	b.inFlight.Add(1)
+	return true
+}
+
+// Stop disconnects the bot with the given quit reason, causing Run to return. Commands that are still running are
+// given up to ShutdownGrace to finish replying before the connection is closed.
+func (b *Bot) Stop(reason string) {
+	b.inFlightMu.Lock()
+	b.stopping = true
+	b.inFlightMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(b.cfg().ShutdownGrace):
+		warnf("Timed out waiting for running commands to finish")
+	}
+
+	if reason != "" {
+		b.irc.QuitMessage = reason
+	}
+
+	b.irc.Quit()
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+)
+
+// savedState is the runtime state written by ~save, and restored on startup. It is kept separate from the config,
+// which can't be rewritten without losing its comments. Admins are only ever changed in the config, so they are not
+// part of it.
+type savedState struct {
+	Channels []string      `toml:"channels"` // joined at runtime with ~join, followed by any key
+	Pinned   []string      `toml:"pinned"`   // those of Channels joined with --pin
+	Ignores  []savedIgnore `toml:"ignores"`  // added at runtime with ~ignore
+}
+
+type savedIgnore struct {
+	Mask    string    `toml:"mask"`
+	Expires time.Time `toml:"expires,omitempty"`
+}
+
+// currentState collects the runtime state that is not already in the config.
+func (b *Bot) currentState() savedState {
+	var state savedState
+
+	b.channelsMu.Lock()
+	for _, ch := range b.extraChannels {
+		state.Channels = append(state.Channels, ch)
+	}
+
+	for ch := range b.pinnedChannels {
+		state.Pinned = append(state.Pinned, ch)
+	}
+	b.channelsMu.Unlock()
+
+	configIgnores := b.cfg().Ignores
+	b.ignoresMu.Lock()
+	b.sweepIgnoresLocked()
+	for _, i := range b.ignores {
+		if !containsFold(configIgnores, i.mask) {
+			state.Ignores = append(state.Ignores, savedIgnore{Mask: i.mask, Expires: i.expires})
+		}
+	}
+	b.ignoresMu.Unlock()
+
+	sort.Strings(state.Channels)
+	sort.Strings(state.Pinned)
+	sort.Slice(state.Ignores, func(i, j int) bool { return state.Ignores[i].Mask < state.Ignores[j].Mask })
+	return state
+}
+
+// saveState writes state to path. It is written to a temporary file which then replaces path, so that a
+// crash part way through can't leave a corrupt state file behind.
+func saveState(path string, state savedState) error {
+	data, err := toml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadState restores the runtime state saved at path, if there is any. Channels are joined once connected.
+func (b *Bot) loadState(path string) error {
+	tree, err := toml.LoadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var state savedState
+	if err := tree.Unmarshal(&state); err != nil {
+		return fmt.Errorf("could not parse state: %w", err)
+	}
+
+	b.channelsMu.Lock()
+	for _, entry := range state.Channels {
+		ch, _ := splitChannelKey(entry)
+		b.extraChannels[strings.ToLower(ch)] = entry
+	}
+
+	for _, ch := range state.Pinned {
+		b.pinnedChannels[strings.ToLower(ch)] = true
+	}
+	b.channelsMu.Unlock()
+
+	now := time.Now()
+	for _, i := range state.Ignores {
+		switch {
+		case i.Expires.IsZero():
+			err = b.addIgnore(i.Mask, 0)
+		case now.Before(i.Expires):
+			err = b.addIgnore(i.Mask, i.Expires.Sub(now))
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid ignore: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveCmd is the callback for the ~save IRC command, and saves channels joined and ignores added at runtime so that
+// they survive a restart.
+func (b *Bot) SaveCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	path := b.cfg().StateFile
+	state := b.currentState()
+	if err := saveState(path, state); err != nil {
+		errorf("Unable to save state: %s", err)
+		reply("Unable to save state: %s", err)
+		return
+	}
+
+	reply("Saved %d channels and %d ignores to %s", len(state.Channels), len(state.Ignores), path)
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// commandStats counts how many times each command has been run, for ~stats. Unlike the metrics, the counts can be
+// reset, and don't need metrics_addr to be set to be seen.
+type commandStats struct {
+	mu     sync.Mutex
+	counts map[string]uint64 // keyed by command name
+}
+
+func newCommandStats() *commandStats {
+	return &commandStats{counts: make(map[string]uint64)}
+}
+
+// commandRun records an invocation of the named command.
+func (s *commandStats) commandRun(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[name]++
+}
+
+// reset forgets all counts.
+func (s *commandStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = make(map[string]uint64)
+}
+
+// String returns each command and its count, most run first.
+func (s *commandStats) String() string {
+	s.mu.Lock()
+	counts := make(map[string]uint64, len(s.counts))
+	names := make([]string, 0, len(s.counts))
+	for name, count := range s.counts {
+		counts[name] = count
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+
+		return names[i] < names[j]
+	})
+
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, fmt.Sprintf("%s: %d", name, counts[name]))
+	}
+
+	return strings.Join(out, ", ")
+}
+
+// StatsCmd is the callback for the ~stats IRC command. It reports how many times each command has been run since
+// startup, or admins can use "stats reset" to start counting again.
+func (b *Bot) StatsCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	switch strings.TrimSpace(args) {
+	case "":
+	case "reset":
+		if !b.isAdmin(ctx.Message) {
+			reply("Permission denied")
+			return
+		}
+
+		b.stats.reset()
+		reply("Command counts reset")
+		return
+
+	default:
+		reply("Usage: stats [reset]")
+		return
+	}
+
+	stats := b.stats.String()
+	if stats == "" {
+		reply("No commands run yet")
+		return
+	}
+
+	reply("Commands run: %s", stats)
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	mainFileHeader  = "-- prog.go --\n"
+	stdinFileHeader = "-- goplay_irc_stdin.go --\n"
+)
+
+// stdinFile is a file added to programs that were given stdin. It replaces os.Stdin with a pipe that is fed the
+// input, before main runs.
+const stdinFile = `package main
+
+import "os"
+
+func init() {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+
+	go func() {
+		w.WriteString(%s)
+		w.Close()
+	}()
+
+	os.Stdin = r
+}
+`
+
+// withStdin returns code with an extra file that feeds stdin to the program's os.Stdin. The playground has no way
+// to provide stdin itself, but does accept multiple files in txtar format, so it is emulated with a pipe.
+func withStdin(code, stdin string) string {
+	if !strings.HasPrefix(code, "-- ") {
+		code = mainFileHeader + code
+	}
+
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+
+	return code + stdinFileHeader + fmt.Sprintf(stdinFile, strconv.Quote(stdin))
+}
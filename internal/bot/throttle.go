@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle is a token bucket that paces outgoing messages, so that long replies don't get the bot disconnected for
+// excess flood. Up to burst messages can be sent at once, after which they are sent at rate per second.
+type throttle struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newThrottle returns a throttle allowing rate messages per second, or nil if rate is not positive. A nil throttle
+// never waits.
+func newThrottle(rate float64, burst int) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &throttle{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a message may be sent. Callers are served in the order they called wait, as each reserves a
+// token (possibly driving the bucket negative) before sleeping.
+func (t *throttle) wait() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+
+	t.last = now
+	t.tokens--
+	deficit := -t.tokens
+	t.mu.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / t.rate * float64(time.Second)))
+	}
+}
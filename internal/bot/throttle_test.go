@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleDisabled(t *testing.T) {
+	if th := newThrottle(-1, 4); th != nil {
+		t.Fatalf("newThrottle(-1) = %v, want nil", th)
+	}
+
+	var th *throttle
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		th.wait()
+	}
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("nil throttle waited %s", elapsed)
+	}
+}
+
+func TestThrottleBurst(t *testing.T) {
+	const rate = 50 // one message every 20ms once the burst is used
+	th := newThrottle(rate, 3)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		th.wait()
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("burst of 3 took %s, want no waiting", elapsed)
+	}
+
+	for i := 0; i < 2; i++ {
+		th.wait()
+	}
+
+	// Two more messages need two more tokens, at 20ms each. Some may have been earned during the burst
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("2 messages after the burst took %s, want at least 30ms", elapsed)
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// makeTLSConfig builds the tls.Config used for the IRC connection. A nil config is returned if nothing needs to be
+// changed from the defaults
+func makeTLSConfig(c *BotConfig) (*tls.Config, error) {
+	if c.CACertFile == "" && c.TLSCertFile == "" && c.TLSKeyFile == "" && c.TLSServerName == "" &&
+		!c.TLSInsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{ServerName: c.TLSServerName}
+	if c.TLSInsecureSkipVerify {
+		warnf("!!! tls_insecure_skip_verify is set, the server's certificate will NOT be verified. The connection is " +
+			"open to interception, consider ca_cert_file instead !!!")
+		config.InsecureSkipVerify = true
+	}
+	if c.CACertFile != "" {
+		pool, err := loadCACertPool(c.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		if c.TLSCertFile == "" || c.TLSKeyFile == "" {
+			return nil, errors.New("tls_cert_file and tls_key_file must be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// loadCACertPool reads a PEM encoded CA bundle from path, and returns a pool containing only those certificates.
+func loadCACertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificates found in CA bundle %q", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ergochat/irc-go/ircmsg"
+)
+
+// markConnected records when the bot last connected to the server, for ~uptime, and by the server's clock according
+// to msg, which ended registration, for isReplayed.
+func (b *Bot) markConnected(msg ircmsg.Message) {
+	b.connectedMu.Lock()
+	defer b.connectedMu.Unlock()
+	b.connectedAt = time.Now()
+	b.registeredAt = serverTime(msg)
+	b.replayWarned = false
+}
+
+// UptimeCmd is the callback for the ~uptime IRC command, and reports how long the bot has been running, and how long
+// it has been connected. These differ once the bot has had to reconnect.
+func (b *Bot) UptimeCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	b.connectedMu.Lock()
+	connectedAt := b.connectedAt
+	b.connectedMu.Unlock()
+
+	if connectedAt.IsZero() {
+		reply("Up %s, not connected", humanDuration(time.Since(b.started)))
+		return
+	}
+
+	reply("Up %s, connected for %s", humanDuration(time.Since(b.started)), humanDuration(time.Since(connectedAt)))
+}
+
+// humanDuration formats d in days, hours, and minutes, eg 3d4h12m, leaving out any leading units that are zero.
+// Durations under a minute are given in seconds.
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh%dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
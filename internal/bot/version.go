@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the version of the bot, it is intended to be set at build time with
+// -ldflags "-X github.com/A-UNDERSCORE-D/goplay-irc/internal/bot.Version=v1.2.3"
+var Version = "dev"
+
+const ircLibModule = "github.com/ergochat/irc-go"
+
+// moduleVersion returns the version of the given dependency that the bot was built with, if known.
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
+
+// VersionCmd is the callback for the ~version IRC command. The playground API does not report which go release it
+// runs, so only versions local to the bot are included.
+func (b *Bot) VersionCmd(_ *CommandContext, _ string, reply ReplyFunc) {
+	reply(fmt.Sprintf(
+		"goplay-irc %s, built with %s, irc-go %s", Version, runtime.Version(), moduleVersion(ircLibModule),
+	))
+}
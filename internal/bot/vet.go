@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// vetResponse is the response from the playground's /vet endpoint
+type vetResponse struct {
+	Errors string
+}
+
+// vetCode runs go vet over code on the playground, returning its diagnostics, if any. goplay has no support for vet,
+// so the endpoint is called directly
+func (b *Bot) vetCode(ctx context.Context, code string) (string, error) {
+	if !b.breaker.allow() {
+		return "", errPlaygroundDown
+	}
+
+	timeout := b.cfg().CompileTimeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out vetResponse
+	err := b.callPlayground(ctx, func() error { return b.postVet(ctx, code, &out) })
+	if errors.Is(err, context.DeadlineExceeded) {
+		b.breaker.failure()
+		return "", fmt.Errorf("vet timed out after %s", timeout)
+	} else if err != nil {
+		b.breaker.failure()
+		return "", err
+	}
+
+	b.breaker.success()
+
+	// Drop the "# package" headers vet adds, they're noise on IRC
+	var lines []string
+	for _, line := range strings.Split(out.Errors, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// postVet sends code to the playground's /vet endpoint, decoding the response into out.
+func (b *Bot) postVet(ctx context.Context, code string, out *vetResponse) error {
+	body := strings.NewReader(url.Values{"body": {code}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, defaultPlayground+"/vet", body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error from playground: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from playground: %s", res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode vet response: %w", err)
+	}
+
+	return nil
+}
+
+// VetCmd is the callback for the ~vet IRC command, and responds with any issues go vet finds in the given snippets
+func (b *Bot) VetCmd(ctx *CommandContext, args string, reply ReplyFunc) {
+	b.forEachSnippet(args, reply, func(code string) string {
+		diagnostics, err := b.vetCode(context.Background(), code)
+		if err != nil {
+			errorf("Unable to vet code: %s", err)
+			ctx.Fail(err)
+			return "Unable to vet: " + explainOrTrim(err.Error())
+		}
+
+		if diagnostics == "" {
+			return "vet: no issues"
+		}
+
+		return "vet: " + diagnostics
+	})
+}
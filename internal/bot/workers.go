@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	errQueueFull = errors.New("job queue is full")
+	errStopping  = errors.New("bot is stopping")
+)
+
+// startWorkers starts the goroutines that run queued commands. They run for the lifetime of the process.
+func (b *Bot) startWorkers() {
+	for i := 0; i < b.cfg().Workers; i++ {
+		go b.worker()
+	}
+}
+
+func (b *Bot) worker() {
+	for job := range b.jobs {
+		job()
+	}
+}
+
+// enqueue queues job to be run by a worker. Jobs are counted as in flight from when they are queued, so that Stop
+// waits for them to run. If the queue is full, errQueueFull is returned and job is dropped.
+func (b *Bot) enqueue(job func()) error {
+	if !b.startInFlight() {
+		return errStopping
+	}
+
+	wrapped := func() {
+		defer b.inFlight.Done()
+		job()
+	}
+
+	select {
+	case b.jobs <- wrapped:
+		return nil
+	default:
+		b.inFlight.Done()
+		return errQueueFull
+	}
+}
+
+// callPlayground runs fn, which makes a request to the playground, without exceeding MaxConcurrentCompiles
+// concurrent requests. If ctx is done before fn returns, ctx's error is returned and fn is left to finish in the
+// background, still counting towards the limit.
+func (b *Bot) callPlayground(ctx context.Context, fn func() error) error {
+	select {
+	case b.playgroundSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-b.playgroundSem }()
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,22 +1,62 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/A-UNDERSCORE-D/goplay-irc/internal/bot"
-	"github.com/pelletier/go-toml"
 )
 
+const configPath = "./config.toml"
+
 func main() {
+	local := flag.Bool(
+		"local", false, "read commands from stdin and write replies to stdout, instead of connecting to IRC",
+	)
+	flag.Parse()
+
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	c := &bot.BotConfig{}
-	res, err := toml.LoadFile("./config.toml")
+	c, err := bot.LoadConfig(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g, err := bot.NewGroup(c)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	res.Unmarshal(c)
-	b := bot.New(c)
+	if *local {
+		// Local mode has no networks to connect to, so only the first is used
+		if err := g.Bots()[0].RunLocal(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
 
-	b.Run()
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := g.Reload(configPath); err != nil {
+				log.Print("Unable to reload config: ", err)
+			}
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-stop
+		log.Printf("Received %s, shutting down", sig)
+		g.Stop("Shutting down")
+	}()
+
+	if err := g.Run(); err != nil {
+		log.Fatal(err)
+	}
 }